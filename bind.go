@@ -1,17 +1,18 @@
 package confserver
 
 import (
+	"context"
+	"net/http"
+	"reflect"
+
 	"github.com/gin-gonic/gin"
 	"github.com/go-courier/httptransport"
 	"github.com/go-courier/httptransport/httpx"
-	"github.com/go-courier/httptransport/transformers"
-	"github.com/go-courier/x/context"
+	contextx "github.com/go-courier/x/context"
 	"github.com/julienschmidt/httprouter"
-	"net/http"
-	"reflect"
 )
 
-var rtMgr = httptransport.NewRequestTransformerMgr(nil, nil)
+var rtMgr = httptransport.NewRequestTransformerMgr(bindTransformerMgr, nil)
 
 func init() {
 	rtMgr.SetDefaults()
@@ -23,14 +24,39 @@ func Bind(ctx *gin.Context, obj interface{}) error {
 	if err != nil {
 		return err
 	}
-	err = req.DecodeAndValidate(ctx, httpx.NewRequestInfo(contextWithPathParams(ctx)), obj)
-	if err != nil {
-		return err
+
+	// multipart 上传 读取 body 前限制请求大小, 超限返回 413
+	var decodeCtx context.Context = ctx
+	var upload *multipartState
+	if isMultipart(ctx.Request) {
+		if upload, err = limitMultipartBody(ctx); err != nil {
+			return err
+		}
+		decodeCtx = contextx.WithValue(ctx, contextKeyMultipartState{}, upload)
+	}
+
+	err = req.DecodeAndValidate(decodeCtx, httpx.NewRequestInfo(contextWithPathParams(ctx)), obj)
+	// 读取 body 超限时 无论字段是否可选均返回 413
+	if upload != nil {
+		if tooLarge := upload.tooLargeErr(); tooLarge != nil {
+			return requestEntityTooLarge(tooLarge)
+		}
 	}
-	return nil
+	return err
 }
 
+// contextWithPathParams 直接复用 gin 已解析的 ctx.Params, 无需按路由模板重新解析
 func contextWithPathParams(ctx *gin.Context) *http.Request {
-	params, _ := transformers.NewPathnamePattern(ctx.FullPath()).Parse(ctx.Request.URL.Path)
-	return ctx.Request.WithContext(context.WithValue(ctx.Request.Context(), httprouter.ParamsKey, params))
+	if len(ctx.Params) == 0 {
+		return ctx.Request
+	}
+	return ctx.Request.WithContext(contextx.WithValue(ctx.Request.Context(), httprouter.ParamsKey, httprouterParams(ctx.Params)))
+}
+
+func httprouterParams(params gin.Params) httprouter.Params {
+	p := make(httprouter.Params, len(params))
+	for i := range params {
+		p[i] = httprouter.Param{Key: params[i].Key, Value: params[i].Value}
+	}
+	return p
 }
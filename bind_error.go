@@ -0,0 +1,109 @@
+package confserver
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-courier/httptransport/httpx"
+	"github.com/go-courier/httptransport/validator"
+	"github.com/go-courier/statuserror"
+	trace2 "github.com/kunlun-qilian/confserver/pkg/trace"
+)
+
+// ErrorLocalizer 本地化错误信息, field 为 nil 时为 statusErr.Msg, 否则为 field.Msg
+type ErrorLocalizer func(c *gin.Context, statusErr *statuserror.StatusErr, field *statuserror.ErrorField) string
+
+var errorLocalizer ErrorLocalizer
+
+// SetErrorLocalizer 设置 WriteError / BindErrorHandler 输出前的错误信息本地化
+func SetErrorLocalizer(localizer ErrorLocalizer) {
+	errorLocalizer = localizer
+}
+
+// BindError 将 Bind 返回的错误统一为 statuserror, 校验错误集合转换为 400 及逐字段的 ErrorFields,
+// 其他错误为 500
+func BindError(err error) *statuserror.StatusErr {
+	if err == nil {
+		return nil
+	}
+
+	if statusErr, ok := statuserror.IsStatusErr(err); ok {
+		// go-courier 参数校验失败的 key 为 badRequest, 统一为 BadRequest
+		if statusErr.Key == courierBadRequestKey && statusErr.StatusCode() == http.StatusBadRequest {
+			normalized := *statusErr
+			normalized.Key = badRequestKey
+			return &normalized
+		}
+		return statusErr
+	}
+
+	var errSet *validator.ErrorSet
+	if errors.As(err, &errSet) {
+		return badRequest(err, errSet.ToErrorFields())
+	}
+
+	return internalServerError(err)
+}
+
+const (
+	badRequestKey        = "BadRequest"
+	courierBadRequestKey = "badRequest"
+)
+
+// badRequest Bind 及 OpenAPI 请求校验失败时统一的 400, fields 为逐字段的位置及错误信息
+func badRequest(err error, fields statuserror.ErrorFields) *statuserror.StatusErr {
+	statusErr := statuserror.Wrap(err, http.StatusBadRequest, badRequestKey, "invalid parameters", "")
+	statusErr.ErrorFields = fields
+	return statusErr
+}
+
+// internalServerError 非 statuserror 的错误统一为 500, 错误信息可能包含内部细节, 仅 debug 模式下作为 desc 输出
+func internalServerError(err error) *statuserror.StatusErr {
+	desc := ""
+	if gin.IsDebugging() {
+		desc = err.Error()
+	}
+	return statuserror.Wrap(err, http.StatusInternalServerError, "InternalServerError", "internal server error", desc)
+}
+
+func localizeStatusErr(c *gin.Context, statusErr *statuserror.StatusErr) *statuserror.StatusErr {
+	if errorLocalizer == nil {
+		return statusErr
+	}
+
+	localized := statusErr.WithMsg(errorLocalizer(c, statusErr, nil))
+	if len(statusErr.ErrorFields) > 0 {
+		localized.ErrorFields = make(statuserror.ErrorFields, len(statusErr.ErrorFields))
+		for i, field := range statusErr.ErrorFields {
+			f := *field
+			f.Msg = errorLocalizer(c, statusErr, field)
+			localized.ErrorFields[i] = &f
+		}
+	}
+	return localized
+}
+
+// BindErrorHandler 统一输出 handler 通过 c.Error 记录且未写出响应的错误
+func BindErrorHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		if c.Writer.Written() || len(c.Errors) == 0 {
+			return
+		}
+		writeStatusErr(c, BindError(c.Errors.Last().Err))
+	}
+}
+
+func writeStatusErr(c *gin.Context, statusErr *statuserror.StatusErr) {
+	if statusErr.ID == "" {
+		statusErr = statusErr.WithID(trace2.TraceIDFromContext(c.Request.Context()))
+	}
+	statusErr = localizeStatusErr(c, statusErr)
+
+	if err := httpx.ResponseFrom(statusErr).WriteTo(c.Writer, c.Request, resolveTransformer(c.GetHeader("Accept"))); err != nil && !c.Writer.Written() {
+		c.JSON(statusErr.StatusCode(), statusErr)
+	}
+	c.Abort()
+}
@@ -0,0 +1,191 @@
+package confserver
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-courier/httptransport/validator"
+	"github.com/go-courier/statuserror"
+)
+
+type bindErrorBody struct {
+	Name string `json:"name" validate:"@string[1,8]"`
+}
+
+type bindErrorReq struct {
+	ID    int           `name:"id" in:"path" validate:"@int[1,]"`
+	Size  int           `name:"size" in:"query" validate:"@int[1,100]"`
+	Token string        `name:"X-Token" in:"header"`
+	Data  bindErrorBody `in:"body"`
+}
+
+func TestBindError(t *testing.T) {
+	errSet := validator.NewErrorSet()
+	errSet.AddErr(errors.New("too large"), validator.Location("body"), "file")
+
+	notFound := statuserror.Wrap(errors.New("x"), http.StatusNotFound, "NotFound")
+
+	cases := []struct {
+		name   string
+		err    error
+		status int
+		key    string
+		fields []string
+	}{
+		{name: "validation error set", err: errSet, status: http.StatusBadRequest, key: "BadRequest", fields: []string{"body.file"}},
+		{name: "courier bad request", err: statuserror.Wrap(errors.New(""), http.StatusBadRequest, "badRequest").AppendErrorField("query", "size", "missing"), status: http.StatusBadRequest, key: "BadRequest", fields: []string{"query.size"}},
+		{name: "status error", err: notFound, status: http.StatusNotFound, key: "NotFound"},
+		{name: "plain error", err: errors.New("boom"), status: http.StatusInternalServerError, key: "InternalServerError"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			statusErr := BindError(c.err)
+			if statusErr.StatusCode() != c.status || statusErr.Key != c.key {
+				t.Fatalf("BindError = %d %s, want %d %s", statusErr.StatusCode(), statusErr.Key, c.status, c.key)
+			}
+			if got := errorFieldLocations(statusErr.ErrorFields); strings.Join(got, ",") != strings.Join(c.fields, ",") {
+				t.Fatalf("fields = %v, want %v", got, c.fields)
+			}
+		})
+	}
+
+	if BindError(nil) != nil {
+		t.Fatal("BindError(nil) should be nil")
+	}
+}
+
+func errorFieldLocations(fields statuserror.ErrorFields) []string {
+	var locations []string
+	for _, f := range fields {
+		locations = append(locations, f.In+"."+f.Field)
+	}
+	sort.Strings(locations)
+	return locations
+}
+
+func decodeStatusErr(t *testing.T, rw *httptest.ResponseRecorder) *statuserror.StatusErr {
+	t.Helper()
+
+	statusErr := &statuserror.StatusErr{}
+	if err := json.Unmarshal(rw.Body.Bytes(), statusErr); err != nil {
+		t.Fatalf("decode %s: %v", rw.Body, err)
+	}
+	return statusErr
+}
+
+// Bind 的校验错误按参数位置输出 ErrorFields
+func TestBindErrorFieldLocations(t *testing.T) {
+	r := gin.New()
+	r.POST("/items/:id", func(c *gin.Context) {
+		if err := Bind(c, &bindErrorReq{}); err != nil {
+			WriteError(c, err)
+			return
+		}
+		c.Status(http.StatusOK)
+	})
+
+	rw := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/items/0?size=1000", strings.NewReader(`{"name":"too long name"}`))
+	req.Header.Set("Content-Type", mimeJSON)
+	r.ServeHTTP(rw, req)
+
+	if rw.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want 400", rw.Code)
+	}
+	statusErr := decodeStatusErr(t, rw)
+	if statusErr.Key != "BadRequest" {
+		t.Fatalf("key = %s", statusErr.Key)
+	}
+	want := []string{"body.name", "header.X-Token", "path.id", "query.size"}
+	if got := errorFieldLocations(statusErr.ErrorFields); strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Fatalf("fields = %v, want %v", got, want)
+	}
+}
+
+func TestSetErrorLocalizer(t *testing.T) {
+	SetErrorLocalizer(func(c *gin.Context, statusErr *statuserror.StatusErr, field *statuserror.ErrorField) string {
+		if field != nil {
+			return c.GetHeader("Accept-Language") + ":" + field.Field
+		}
+		return c.GetHeader("Accept-Language") + ":" + statusErr.Key
+	})
+	defer SetErrorLocalizer(nil)
+
+	r := gin.New()
+	r.GET("/", Handle(echoName))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept-Language", "zh")
+	rw := httptest.NewRecorder()
+	r.ServeHTTP(rw, req)
+
+	statusErr := decodeStatusErr(t, rw)
+	if statusErr.Msg != "zh:BadRequest" {
+		t.Fatalf("msg = %q", statusErr.Msg)
+	}
+	if len(statusErr.ErrorFields) != 1 || statusErr.ErrorFields[0].Msg != "zh:name" {
+		t.Fatalf("fields = %v", statusErr.ErrorFields)
+	}
+}
+
+// OpenAPI 请求校验与 Bind 输出相同格式的 400, 并经过 ErrorLocalizer
+func TestOpenAPIRequestValidatorErrorShape(t *testing.T) {
+	SetErrorLocalizer(func(c *gin.Context, statusErr *statuserror.StatusErr, field *statuserror.ErrorField) string {
+		if field != nil {
+			return "localized " + field.Msg
+		}
+		return "localized " + statusErr.Msg
+	})
+	defer SetErrorLocalizer(nil)
+
+	specFile := filepath.Join(t.TempDir(), "openapi.json")
+	s := &Server{OpenAPISpec: specFile, RequestValidation: ValidationEnforce}
+	s.SetDefaults()
+	s.Init()
+	s.SvcRootRouter().GET("/items", Handle(echoName))
+
+	path := joinRoutePath(s.SvcRootRouter().BasePath(), "/items")
+	spec := fmt.Sprintf(`{
+  "openapi": "3.0.3",
+  "info": {"title": "test", "version": "1.0.0"},
+  "paths": {
+    %q: {
+      "get": {
+        "parameters": [{"name": "size", "in": "query", "required": true, "schema": {"type": "integer", "minimum": 1}}],
+        "responses": {"200": {"description": "ok"}}
+      }
+    }
+  }
+}`, path)
+	if err := os.WriteFile(specFile, []byte(spec), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	rw := httptest.NewRecorder()
+	s.Engine().ServeHTTP(rw, httptest.NewRequest(http.MethodGet, path+"?size=0", nil))
+
+	if rw.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want 400", rw.Code)
+	}
+	statusErr := decodeStatusErr(t, rw)
+	if statusErr.Key != "BadRequest" || statusErr.Msg != "localized invalid parameters" {
+		t.Fatalf("key = %s msg = %s", statusErr.Key, statusErr.Msg)
+	}
+	if len(statusErr.Sources) == 0 {
+		t.Fatal("source missing")
+	}
+	if len(statusErr.ErrorFields) != 1 || statusErr.ErrorFields[0].In != "query" || statusErr.ErrorFields[0].Field != "size" ||
+		!strings.HasPrefix(statusErr.ErrorFields[0].Msg, "localized ") {
+		t.Fatalf("fields = %v", statusErr.ErrorFields)
+	}
+}
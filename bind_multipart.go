@@ -0,0 +1,465 @@
+package confserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-courier/httptransport/httpx"
+	"github.com/go-courier/httptransport/transformers"
+	"github.com/go-courier/httptransport/validator"
+	"github.com/go-courier/statuserror"
+	reflectx "github.com/go-courier/x/reflect"
+	typesx "github.com/go-courier/x/types"
+)
+
+// MultipartLimits multipart/form-data 上传限制, 0 表示不限制
+type MultipartLimits struct {
+	// 单个请求超过后 文件写入临时文件
+	MaxMemory ByteSize
+	// 单个请求 body 上限
+	MaxRequestSize ByteSize
+	// 单个文件上限, 字段可通过 maxSize:"10MB" tag 覆盖
+	MaxFileSize ByteSize
+}
+
+var defaultMultipartLimits = MultipartLimits{
+	MaxMemory: 32 << 20,
+}
+
+// contextKeyMultipartLimits gin.Context 中 Server 的 multipart 上传限制
+const contextKeyMultipartLimits = "confserver.multipartLimits"
+
+// multipartLimitsHandler 将 Server 的上传限制写入 gin.Context, 多个 Server 互不影响
+func (s *Server) multipartLimitsHandler() gin.HandlerFunc {
+	limits := &MultipartLimits{
+		MaxMemory:      s.MultipartMaxMemory,
+		MaxRequestSize: s.MultipartMaxRequestSize,
+		MaxFileSize:    s.MultipartMaxFileSize,
+	}
+	if limits.MaxMemory <= 0 {
+		limits.MaxMemory = defaultMultipartLimits.MaxMemory
+	}
+
+	return func(c *gin.Context) {
+		c.Set(contextKeyMultipartLimits, limits)
+		c.Next()
+	}
+}
+
+func multipartLimitsOf(c *gin.Context) *MultipartLimits {
+	if limits, ok := c.Value(contextKeyMultipartLimits).(*MultipartLimits); ok {
+		return limits
+	}
+	return &defaultMultipartLimits
+}
+
+// bindTransformerMgr 标准 transformer 外, 使用 multipartTransformer 替换默认的 multipart 实现
+var bindTransformerMgr = &transformers.TransformerFactory{}
+
+func init() {
+	bindTransformerMgr.Register(
+		&transformers.TransformerJSON{},
+		&transformers.XMLTransformer{},
+		&transformers.TransformerPlainText{},
+		&transformers.TransformerHTMLText{},
+		&transformers.TransformerOctetStream{},
+		&transformers.TransformerURLEncoded{},
+		&multipartTransformer{},
+	)
+}
+
+// ErrFieldTooLarge multipart 字段超出 maxSize 或 MaxFileSize
+type ErrFieldTooLarge struct {
+	Name  string
+	Limit ByteSize
+}
+
+func (e *ErrFieldTooLarge) Error() string {
+	limit, _ := e.Limit.MarshalText()
+	return fmt.Sprintf("multipart field %q exceeds %s", e.Name, limit)
+}
+
+type contextKeyMultipartState struct{}
+
+// multipartState 单次 Bind 的上传状态, 记录超限错误, 并在请求结束时清理临时文件
+type multipartState struct {
+	ctx    context.Context
+	limits *MultipartLimits
+
+	mu sync.Mutex
+	// 转写 body 的 goroutine 中写入
+	tooLarge error
+}
+
+func isMultipart(r *http.Request) bool {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get(httpx.HeaderContentType))
+	return strings.HasPrefix(mediaType, "multipart/")
+}
+
+// limitMultipartBody 在读取 body 前校验 Content-Length, 并限制实际读取的字节数
+func limitMultipartBody(c *gin.Context) (*multipartState, error) {
+	state := &multipartState{ctx: c.Request.Context(), limits: multipartLimitsOf(c)}
+
+	if limit := int64(state.limits.MaxRequestSize); limit > 0 {
+		if c.Request.ContentLength > limit {
+			return nil, requestEntityTooLarge(&http.MaxBytesError{Limit: limit})
+		}
+		c.Request.Body = &multipartBody{
+			ReadCloser: http.MaxBytesReader(c.Writer, c.Request.Body, limit),
+			state:      state,
+		}
+	}
+
+	return state, nil
+}
+
+// multipartBody 超限发生在 part header 中时 multipart.Reader 返回的是格式错误, 因此在读取 body 时记录
+type multipartBody struct {
+	io.ReadCloser
+	state *multipartState
+}
+
+func (b *multipartBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if err != nil && err != io.EOF {
+		b.state.record(err)
+	}
+	return n, err
+}
+
+func (state *multipartState) record(err error) {
+	var fieldErr *ErrFieldTooLarge
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &fieldErr) || errors.As(err, &maxBytesErr) || errors.Is(err, multipart.ErrMessageTooLarge) {
+		state.mu.Lock()
+		defer state.mu.Unlock()
+		if state.tooLarge == nil {
+			state.tooLarge = err
+		}
+	}
+}
+
+// tooLargeErr 请求或字段超限时返回对应错误
+func (state *multipartState) tooLargeErr() error {
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	return state.tooLarge
+}
+
+func requestEntityTooLarge(err error) *statuserror.StatusErr {
+	return statuserror.Wrap(err, http.StatusRequestEntityTooLarge, "RequestEntityTooLarge", "request entity too large", err.Error())
+}
+
+type multipartParamKind int
+
+const (
+	multipartValue multipartParamKind = iota
+	multipartFileHeader
+	multipartFileHeaders
+	multipartFileReader
+)
+
+type multipartParam struct {
+	transformers.RequestParameter
+	kind multipartParamKind
+	// 字段大小上限, 0 时文件字段使用 MaxFileSize
+	maxSize ByteSize
+}
+
+/*
+multipartTransformer for multipart/form-data
+
+支持 *multipart.FileHeader, []*multipart.FileHeader 及 io.ReadCloser 字段,
+按字段边读边校验大小, 超出 MaxMemory 的文件写入临时文件, 请求结束后清理
+*/
+type multipartTransformer struct {
+	params []multipartParam
+}
+
+func (multipartTransformer) Names() []string {
+	return []string{"multipart/form-data", "multipart", "form-data"}
+}
+
+func (multipartTransformer) NamedByTag() string {
+	return "name"
+}
+
+func (t *multipartTransformer) String() string {
+	return t.Names()[0]
+}
+
+func (multipartTransformer) New(ctx context.Context, typ typesx.Type) (transformers.Transformer, error) {
+	t := &multipartTransformer{}
+
+	typ = typesx.Deref(typ)
+	if typ.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("content transformer `%s` should be used for struct type", t)
+	}
+
+	errSet := validator.NewErrorSet()
+
+	transformers.EachParameter(ctx, typ, func(p *transformers.Parameter) bool {
+		param, err := newMultipartParam(ctx, p)
+		if err != nil {
+			errSet.AddErr(err, p.Name)
+			return false
+		}
+		t.params = append(t.params, *param)
+		return true
+	})
+
+	if err := errSet.Err(); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+func newMultipartParam(ctx context.Context, p *transformers.Parameter) (*multipartParam, error) {
+	param := &multipartParam{kind: multipartKindOf(p.Type)}
+	param.Parameter = *p
+
+	opt := &param.TransformerOption
+	opt.Name = p.Name
+	if tag, ok := p.Tags["name"]; ok {
+		opt.Omitempty = tag.HasFlag("omitempty")
+	}
+	if tag, ok := p.Tags["mime"]; ok {
+		opt.MIME = tag.Name()
+	}
+	if tag, ok := p.Tags["maxSize"]; ok {
+		if err := param.maxSize.UnmarshalText([]byte(tag)); err != nil {
+			return nil, err
+		}
+	}
+
+	// io.ReadCloser 直接打开上传文件, 无需 transformer 与 validator
+	if param.kind == multipartFileReader {
+		return param, nil
+	}
+
+	typ := p.Type
+	switch typ.Kind() {
+	case reflect.Array, reflect.Slice:
+		if !(typ.Elem().PkgPath() == "" && typ.Elem().Kind() == reflect.Uint8) {
+			opt.Explode = true
+			typ = typ.Elem()
+		}
+	}
+
+	transformer, err := transformers.NewTransformer(ctx, typ, *opt)
+	if err != nil {
+		return nil, err
+	}
+	param.Transformer = transformer
+
+	param.Validator, err = transformers.NewValidator(ctx, p.Type, p.Tags, opt.Omitempty, transformer)
+	if err != nil {
+		return nil, err
+	}
+
+	return param, nil
+}
+
+func multipartKindOf(typ typesx.Type) multipartParamKind {
+	isFileHeader := func(typ typesx.Type) bool {
+		typ = typesx.Deref(typ)
+		return typ.PkgPath() == "mime/multipart" && typ.Name() == "FileHeader"
+	}
+
+	switch {
+	case typ.Kind() == reflect.Ptr && isFileHeader(typ):
+		return multipartFileHeader
+	case typ.Kind() == reflect.Slice && typ.Elem().Kind() == reflect.Ptr && isFileHeader(typ.Elem()):
+		return multipartFileHeaders
+	case typ.Kind() == reflect.Interface && typ.PkgPath() == "io" && typ.Name() == "ReadCloser":
+		return multipartFileReader
+	}
+	return multipartValue
+}
+
+func (t *multipartTransformer) NewValidator(ctx context.Context, typ typesx.Type) (validator.Validator, error) {
+	v, err := t.New(ctx, typ)
+	if err != nil {
+		return nil, err
+	}
+	return v.(*multipartTransformer), nil
+}
+
+func (t *multipartTransformer) Validate(v interface{}) error {
+	rv, ok := v.(reflect.Value)
+	if !ok {
+		rv = reflect.ValueOf(v)
+	}
+	rv = reflectx.Indirect(rv)
+
+	errSet := validator.NewErrorSet()
+
+	for i := range t.params {
+		p := t.params[i]
+		fieldValue := p.FieldValue(rv)
+
+		if p.Validator != nil {
+			if err := p.Validator.Validate(fieldValue); err != nil {
+				errSet.AddErr(err, p.Name)
+			}
+			continue
+		}
+
+		if p.kind == multipartFileReader && !p.TransformerOption.Omitempty && fieldValue.IsNil() {
+			errSet.AddErr(validator.MissingRequired{}, p.Name)
+		}
+	}
+
+	return errSet.Err()
+}
+
+func (t *multipartTransformer) EncodeTo(ctx context.Context, w io.Writer, v interface{}) error {
+	return fmt.Errorf("content transformer `%s` only supports decoding", t)
+}
+
+func (t *multipartTransformer) DecodeFrom(ctx context.Context, r io.Reader, v interface{}, headers ...textproto.MIMEHeader) error {
+	rv, ok := v.(reflect.Value)
+	if !ok {
+		rv = reflect.ValueOf(v)
+	}
+
+	_, params, err := mime.ParseMediaType(transformers.MIMEHeader(headers...).Get(httpx.HeaderContentType))
+	if err != nil {
+		return err
+	}
+
+	state, _ := ctx.Value(contextKeyMultipartState{}).(*multipartState)
+	if state == nil {
+		state = &multipartState{ctx: ctx, limits: &defaultMultipartLimits}
+	}
+
+	form, err := t.readForm(r, params["boundary"], state.limits)
+	if err != nil {
+		state.record(err)
+		// 仅有 body 位置的错误会被 ErrorSet.ToErrorFields 丢弃, 导致可选字段读取失败时仍解码成功,
+		// 因此按字段返回, 无法确定字段时字段名为空
+		name := ""
+		var fieldErr *ErrFieldTooLarge
+		if errors.As(err, &fieldErr) {
+			name = fieldErr.Name
+		}
+		errSet := validator.NewErrorSet()
+		errSet.AddErr(err, name)
+		return errSet
+	}
+
+	var opened []io.Closer
+	context.AfterFunc(state.ctx, func() {
+		for _, f := range opened {
+			_ = f.Close()
+		}
+		_ = form.RemoveAll()
+	})
+
+	errSet := validator.NewErrorSet()
+
+	for i := range t.params {
+		p := t.params[i]
+		fieldValue := p.FieldValue(rv)
+		files := form.File[p.Name]
+
+		switch p.kind {
+		case multipartFileHeader:
+			if len(files) > 0 {
+				fieldValue.Set(reflect.ValueOf(files[0]))
+			}
+		case multipartFileHeaders:
+			if len(files) > 0 {
+				fieldValue.Set(reflect.ValueOf(files).Convert(fieldValue.Type()))
+			}
+		case multipartFileReader:
+			if len(files) > 0 {
+				f, err := files[0].Open()
+				if err != nil {
+					errSet.AddErr(err, p.Name)
+					continue
+				}
+				opened = append(opened, f)
+				fieldValue.Set(reflect.ValueOf(f))
+			}
+		default:
+			if values, ok := form.Value[p.Name]; ok {
+				st := transformers.NewTransformerSuper(p.Transformer, &p.TransformerOption.CommonTransformOption)
+				if err := st.DecodeFrom(ctx, transformers.NewStringReaders(values), fieldValue.Addr()); err != nil {
+					errSet.AddErr(err, p.Name)
+				}
+			}
+		}
+	}
+
+	return errSet.Err()
+}
+
+// readForm 将 body 逐个 part 转写给 multipart.Reader.ReadForm, 转写时校验字段大小,
+// 超限时立即中断读取, 不必等待整个 body 读完
+func (t *multipartTransformer) readForm(r io.Reader, boundary string, limits *MultipartLimits) (*multipart.Form, error) {
+	pr, pw := io.Pipe()
+	w := multipart.NewWriter(pw)
+
+	go func() {
+		pw.CloseWithError(t.copyParts(multipart.NewReader(r, boundary), w, limits))
+	}()
+
+	form, err := multipart.NewReader(pr, w.Boundary()).ReadForm(int64(limits.MaxMemory))
+	// ReadForm 提前返回时 结束转写
+	pr.CloseWithError(io.ErrClosedPipe)
+	return form, err
+}
+
+func (t *multipartTransformer) copyParts(r *multipart.Reader, w *multipart.Writer, limits *MultipartLimits) error {
+	for {
+		part, err := r.NextPart()
+		if err == io.EOF {
+			return w.Close()
+		}
+		if err != nil {
+			return err
+		}
+
+		dst, err := w.CreatePart(part.Header)
+		if err != nil {
+			return err
+		}
+
+		limit := t.limitOf(part.FormName(), part.FileName() != "", limits)
+		var src io.Reader = part
+		if limit > 0 {
+			src = io.LimitReader(part, int64(limit)+1)
+		}
+
+		n, err := io.Copy(dst, src)
+		if err != nil {
+			return err
+		}
+		if limit > 0 && n > int64(limit) {
+			return &ErrFieldTooLarge{Name: part.FormName(), Limit: limit}
+		}
+	}
+}
+
+func (t *multipartTransformer) limitOf(name string, isFile bool, limits *MultipartLimits) ByteSize {
+	for i := range t.params {
+		if t.params[i].Name == name && t.params[i].maxSize > 0 {
+			return t.params[i].maxSize
+		}
+	}
+	if isFile {
+		return limits.MaxFileSize
+	}
+	return 0
+}
@@ -0,0 +1,305 @@
+package confserver
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-courier/courier"
+	"github.com/go-courier/httptransport"
+	"github.com/go-courier/httptransport/httpx"
+	"github.com/go-courier/statuserror"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+type uploadBody struct {
+	Name     string                `name:"name"`
+	File     *multipart.FileHeader `name:"file"`
+	Optional *multipart.FileHeader `name:"optional,omitempty"`
+	Avatar   *multipart.FileHeader `name:"avatar,omitempty" maxSize:"16B"`
+}
+
+type uploadReq struct {
+	Data uploadBody `in:"body" mime:"multipart"`
+}
+
+type multipartField struct {
+	name string
+	file string
+	size int
+}
+
+func newMultipartRequest(t testing.TB, target string, fields ...multipartField) *http.Request {
+	body := &bytes.Buffer{}
+	w := multipart.NewWriter(body)
+	for _, f := range fields {
+		var (
+			part interface{ Write([]byte) (int, error) }
+			err  error
+		)
+		if f.file != "" {
+			part, err = w.CreateFormFile(f.name, f.file)
+		} else {
+			part, err = w.CreateFormField(f.name)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := part.Write(bytes.Repeat([]byte("a"), f.size)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, target, body)
+	req.Header.Set(httpx.HeaderContentType, w.FormDataContentType())
+	return req
+}
+
+func statusCodeOf(err error) int {
+	if err == nil {
+		return http.StatusOK
+	}
+	var statusErr *statuserror.StatusErr
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode()
+	}
+	return BindError(err).StatusCode()
+}
+
+func TestBindMultipartLimits(t *testing.T) {
+	cases := []struct {
+		name   string
+		limits *Server
+		fields []multipartField
+		status int
+	}{
+		{
+			name:   "ok",
+			limits: &Server{MultipartMaxFileSize: 64},
+			fields: []multipartField{{name: "name", size: 4}, {name: "file", file: "a.txt", size: 64}},
+			status: http.StatusOK,
+		},
+		{
+			name:   "missing required file",
+			fields: []multipartField{{name: "name", size: 4}},
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "required file too large",
+			limits: &Server{MultipartMaxFileSize: 10},
+			fields: []multipartField{{name: "name", size: 4}, {name: "file", file: "a.txt", size: 100}},
+			status: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:   "optional file too large",
+			limits: &Server{MultipartMaxFileSize: 10},
+			fields: []multipartField{{name: "name", size: 4}, {name: "file", file: "a.txt", size: 5}, {name: "optional", file: "b.txt", size: 100}},
+			status: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:   "maxSize tag overrides MaxFileSize",
+			limits: &Server{MultipartMaxFileSize: 1 << 20},
+			fields: []multipartField{{name: "file", file: "a.txt", size: 5}, {name: "avatar", file: "b.png", size: 17}},
+			status: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:   "value field is not limited by MaxFileSize",
+			limits: &Server{MultipartMaxFileSize: 10},
+			fields: []multipartField{{name: "name", size: 100}, {name: "file", file: "a.txt", size: 5}},
+			status: http.StatusOK,
+		},
+		{
+			name:   "request too large",
+			limits: &Server{MultipartMaxRequestSize: 128},
+			fields: []multipartField{{name: "file", file: "a.txt", size: 1024}},
+			status: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:   "optional file exceeds request size",
+			limits: &Server{MultipartMaxRequestSize: 512},
+			fields: []multipartField{{name: "file", file: "a.txt", size: 5}, {name: "optional", file: "b.txt", size: 1024}},
+			status: http.StatusRequestEntityTooLarge,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			s := c.limits
+			if s == nil {
+				s = &Server{}
+			}
+
+			var bindErr error
+			handlerCalled := false
+			r := gin.New()
+			r.Use(s.multipartLimitsHandler())
+			r.POST("/upload", func(ctx *gin.Context) {
+				req := &uploadReq{}
+				if bindErr = Bind(ctx, req); bindErr != nil {
+					WriteError(ctx, bindErr)
+					return
+				}
+				handlerCalled = true
+				ctx.Status(http.StatusOK)
+			})
+
+			req := newMultipartRequest(t, "/upload", c.fields...)
+			// 不带 Content-Length, 校验流式读取时的限制
+			req.ContentLength = -1
+
+			rw := httptest.NewRecorder()
+			r.ServeHTTP(rw, req)
+
+			if got := statusCodeOf(bindErr); got != c.status {
+				t.Fatalf("Bind status = %d, want %d, err: %v", got, c.status, bindErr)
+			}
+			if rw.Code != c.status {
+				t.Fatalf("response status = %d, want %d", rw.Code, c.status)
+			}
+			if handlerCalled != (c.status == http.StatusOK) {
+				t.Fatalf("handler called = %v", handlerCalled)
+			}
+		})
+	}
+}
+
+func TestBindMultipartContentLength(t *testing.T) {
+	s := Server{MultipartMaxRequestSize: 128}
+
+	r := gin.New()
+	r.Use(s.multipartLimitsHandler())
+	r.POST("/upload", func(ctx *gin.Context) {
+		if err := Bind(ctx, &uploadReq{}); err != nil {
+			WriteError(ctx, err)
+			return
+		}
+		ctx.Status(http.StatusOK)
+	})
+
+	rw := httptest.NewRecorder()
+	r.ServeHTTP(rw, newMultipartRequest(t, "/upload", multipartField{name: "file", file: "a.txt", size: 1024}))
+
+	if rw.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("status = %d, want 413", rw.Code)
+	}
+}
+
+// 同一进程中的多个 Server 各自使用自己的上传限制
+func TestMultipartLimitsPerServer(t *testing.T) {
+	small := &Server{MultipartMaxFileSize: 10}
+	large := &Server{MultipartMaxFileSize: 1 << 20}
+
+	newEngine := func(s *Server) *gin.Engine {
+		r := gin.New()
+		r.Use(s.multipartLimitsHandler())
+		r.POST("/upload", func(ctx *gin.Context) {
+			if err := Bind(ctx, &uploadReq{}); err != nil {
+				WriteError(ctx, err)
+				return
+			}
+			ctx.Status(http.StatusOK)
+		})
+		return r
+	}
+	smallEngine, largeEngine := newEngine(small), newEngine(large)
+
+	for _, c := range []struct {
+		engine *gin.Engine
+		status int
+	}{
+		{engine: smallEngine, status: http.StatusRequestEntityTooLarge},
+		{engine: largeEngine, status: http.StatusOK},
+	} {
+		rw := httptest.NewRecorder()
+		c.engine.ServeHTTP(rw, newMultipartRequest(t, "/upload", multipartField{name: "name", size: 4}, multipartField{name: "file", file: "a.txt", size: 100}))
+		if rw.Code != c.status {
+			t.Fatalf("status = %d, want %d", rw.Code, c.status)
+		}
+	}
+}
+
+type courierUpload struct {
+	httpx.MethodPost
+	Data uploadBody `in:"body" mime:"multipart"`
+}
+
+func (courierUpload) Path() string {
+	return "/upload"
+}
+
+var courierUploadCalled bool
+
+func (req *courierUpload) Output(ctx context.Context) (interface{}, error) {
+	courierUploadCalled = true
+	return nil, nil
+}
+
+func TestMountCourierRouteMultipartLimits(t *testing.T) {
+	s := &Server{MultipartMaxFileSize: 10}
+
+	r := gin.New()
+	r.Use(s.multipartLimitsHandler())
+
+	serviceMeta := &httptransport.ServiceMeta{Name: "test"}
+	serviceMeta.SetDefaults()
+
+	router := courier.NewRouter(&courierUpload{})
+	if err := mountCourierRoute(&r.RouterGroup, serviceMeta, httptransport.NewHttpRouteMeta(router.Routes()[0])); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name   string
+		fields []multipartField
+		status int
+	}{
+		{
+			name:   "ok",
+			fields: []multipartField{{name: "name", size: 4}, {name: "file", file: "a.txt", size: 5}},
+			status: http.StatusNoContent,
+		},
+		{
+			name:   "required file too large",
+			fields: []multipartField{{name: "file", file: "a.txt", size: 100}},
+			status: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:   "optional file too large",
+			fields: []multipartField{{name: "name", size: 4}, {name: "file", file: "a.txt", size: 5}, {name: "optional", file: "b.txt", size: 100}},
+			status: http.StatusRequestEntityTooLarge,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			courierUploadCalled = false
+
+			rw := httptest.NewRecorder()
+			r.ServeHTTP(rw, newMultipartRequest(t, "/upload", c.fields...))
+
+			if rw.Code != c.status {
+				t.Fatalf("status = %d, want %d, body: %s", rw.Code, c.status, rw.Body)
+			}
+			if c.status == http.StatusRequestEntityTooLarge {
+				if !strings.Contains(rw.Body.String(), "RequestEntityTooLarge") {
+					t.Fatalf("unexpected body: %s", rw.Body)
+				}
+				if courierUploadCalled {
+					t.Fatal("operator should not be called")
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package confserver
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-courier/httptransport/httpx"
+	"github.com/go-courier/httptransport/transformers"
+	contextx "github.com/go-courier/x/context"
+	"github.com/julienschmidt/httprouter"
+)
+
+type bindPathReq struct {
+	OrgID  string `name:"orgID" in:"path"`
+	UserID int    `name:"userID" in:"path"`
+	Size   int    `name:"size,omitempty" in:"query"`
+}
+
+// contextWithPathParamsByPattern 改用 gin.Params 前按路由模板重新解析 path 的实现, 用于对比
+func contextWithPathParamsByPattern(ctx *gin.Context) *http.Request {
+	params, _ := transformers.NewPathnamePattern(ctx.FullPath()).Parse(ctx.Request.URL.Path)
+	return ctx.Request.WithContext(contextx.WithValue(ctx.Request.Context(), httprouter.ParamsKey, params))
+}
+
+func TestBindPathParams(t *testing.T) {
+	var req bindPathReq
+	var bindErr error
+
+	r := gin.New()
+	r.GET("/orgs/:orgID/users/:userID", func(ctx *gin.Context) {
+		bindErr = Bind(ctx, &req)
+	})
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/orgs/o1/users/42?size=10", nil))
+
+	if bindErr != nil {
+		t.Fatal(bindErr)
+	}
+	if req.OrgID != "o1" || req.UserID != 42 || req.Size != 10 {
+		t.Fatalf("unexpected bind result: %+v", req)
+	}
+}
+
+func BenchmarkBind(b *testing.B) {
+	benchmarks := []struct {
+		name       string
+		pathParams func(ctx *gin.Context) *http.Request
+	}{
+		{name: "pattern", pathParams: contextWithPathParamsByPattern},
+		{name: "ginParams", pathParams: contextWithPathParams},
+	}
+
+	for _, bm := range benchmarks {
+		b.Run(bm.name, func(b *testing.B) {
+			var c *gin.Context
+			r := gin.New()
+			r.GET("/orgs/:orgID/users/:userID", func(ctx *gin.Context) {
+				c = ctx.Copy()
+			})
+			r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/orgs/o1/users/42?size=10", nil))
+
+			rt, err := rtMgr.NewRequestTransformer(c, reflect.TypeOf(&bindPathReq{}))
+			if err != nil {
+				b.Fatal(err)
+			}
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				req := &bindPathReq{}
+				if err := rt.DecodeAndValidate(c, httpx.NewRequestInfo(bm.pathParams(c)), req); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
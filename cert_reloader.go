@@ -0,0 +1,104 @@
+package confserver
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// certReloader 定期检查证书文件, 变更后原子替换, 仅影响新的握手
+type certReloader struct {
+	certFile string
+	keyFile  string
+
+	cert     atomic.Pointer[tls.Certificate]
+	certPEM  []byte
+	notAfter atomic.Pointer[time.Time]
+}
+
+func newCertReloader(certFile string, keyFile string) (*certReloader, error) {
+	r := &certReloader{
+		certFile: certFile,
+		keyFile:  keyFile,
+	}
+	if _, err := r.reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *certReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	return r.cert.Load(), nil
+}
+
+// NotAfter 当前证书过期时间
+func (r *certReloader) NotAfter() time.Time {
+	if t := r.notAfter.Load(); t != nil {
+		return *t
+	}
+	return time.Time{}
+}
+
+func (r *certReloader) watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			changed, err := r.reload()
+			if err != nil {
+				// keep serving the previous certificate
+				logrus.WithField("tag", "tls").WithError(err).Error("reload tls certificate failed")
+				continue
+			}
+			if changed {
+				logrus.WithFields(logrus.Fields{
+					"tag":       "tls",
+					"cert_file": r.certFile,
+					"not_after": r.NotAfter().Format(time.RFC3339),
+				}).Info("tls certificate reloaded")
+			}
+		}
+	}
+}
+
+func (r *certReloader) reload() (bool, error) {
+	certPEM, err := os.ReadFile(r.certFile)
+	if err != nil {
+		return false, err
+	}
+	keyPEM, err := os.ReadFile(r.keyFile)
+	if err != nil {
+		return false, err
+	}
+
+	if r.certPEM != nil && bytes.Equal(certPEM, r.certPEM) {
+		return false, nil
+	}
+
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return false, err
+	}
+
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		return false, err
+	}
+	cert.Leaf = leaf
+	notAfter := leaf.NotAfter
+
+	r.certPEM = certPEM
+	r.cert.Store(&cert)
+	r.notAfter.Store(&notAfter)
+	return true, nil
+}
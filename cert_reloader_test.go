@@ -0,0 +1,136 @@
+package confserver
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+)
+
+// handshakeSerial 建立新的连接并返回服务端证书的序列号
+func handshakeSerial(t *testing.T, url string, pool *x509.CertPool) *big.Int {
+	t.Helper()
+
+	client := &http.Client{Transport: &http.Transport{
+		TLSClientConfig:   &tls.Config{RootCAs: pool},
+		DisableKeepAlives: true,
+	}}
+	resp, err := client.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	return resp.TLS.PeerCertificates[0].SerialNumber
+}
+
+func TestCertReloader(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t)
+	first := ca.issue(t, "server", time.Now().Add(time.Hour), x509.ExtKeyUsageServerAuth)
+	certFile, keyFile := first.writeTo(t, dir, "server")
+
+	certs, err := newCertReloader(certFile, keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !certs.NotAfter().Equal(first.cert.NotAfter) {
+		t.Fatalf("NotAfter = %v, want %v", certs.NotAfter(), first.cert.NotAfter)
+	}
+
+	l, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{GetCertificate: certs.GetCertificate})
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := &http.Server{Handler: http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {})}
+	go srv.Serve(l)
+	defer srv.Close()
+	url := "https://" + l.Addr().String()
+
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.cert)
+
+	if serial := handshakeSerial(t, url, pool); serial.Cmp(first.cert.SerialNumber) != 0 {
+		t.Fatalf("serial = %s, want %s", serial, first.cert.SerialNumber)
+	}
+
+	if changed, err := certs.reload(); err != nil || changed {
+		t.Fatalf("reload unchanged files = %v %v", changed, err)
+	}
+
+	// 新证书与旧私钥不匹配时保留原证书
+	second := ca.issue(t, "server", time.Now().Add(2*time.Hour), x509.ExtKeyUsageServerAuth)
+	if err := os.WriteFile(certFile, second.certPEM, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := certs.reload(); err == nil {
+		t.Fatal("expect error for mismatched key pair")
+	}
+	if serial := handshakeSerial(t, url, pool); serial.Cmp(first.cert.SerialNumber) != 0 {
+		t.Fatalf("serial = %s, want previous %s", serial, first.cert.SerialNumber)
+	}
+	if !certs.NotAfter().Equal(first.cert.NotAfter) {
+		t.Fatalf("NotAfter = %v, want previous %v", certs.NotAfter(), first.cert.NotAfter)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go certs.watch(ctx, 10*time.Millisecond)
+
+	// 轮换完整的证书后新的握手使用新证书
+	second.writeTo(t, dir, "server")
+
+	deadline := time.Now().Add(2 * time.Second)
+	for certs.NotAfter().Equal(first.cert.NotAfter) {
+		if time.Now().After(deadline) {
+			t.Fatal("certificate not reloaded")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if !certs.NotAfter().Equal(second.cert.NotAfter) {
+		t.Fatalf("NotAfter = %v, want %v", certs.NotAfter(), second.cert.NotAfter)
+	}
+	if serial := handshakeSerial(t, url, pool); serial.Cmp(second.cert.SerialNumber) != 0 {
+		t.Fatalf("serial = %s, want %s", serial, second.cert.SerialNumber)
+	}
+}
+
+func TestHealthCertificateExpiry(t *testing.T) {
+	dir := t.TempDir()
+	cert := newTestCA(t).issue(t, "server", time.Now().Add(time.Hour), x509.ExtKeyUsageServerAuth)
+	certFile, keyFile := cert.writeTo(t, dir, "server")
+
+	s := &Server{}
+	s.SetDefaults()
+	s.Init()
+
+	certs, err := newCertReloader(certFile, keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.certs = certs
+
+	rw := httptest.NewRecorder()
+	s.Engine().ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	if got, want := rw.Header().Get("X-TLS-Certificate-Expiry"), cert.cert.NotAfter.Format(time.RFC3339); got != want {
+		t.Fatalf("X-TLS-Certificate-Expiry = %q, want %q", got, want)
+	}
+
+	for _, path := range []string{"/livez", "/readyz", "/startupz"} {
+		rw := httptest.NewRecorder()
+		s.Engine().ServeHTTP(rw, httptest.NewRequest(http.MethodGet, path, nil))
+
+		report := &HealthReport{}
+		if err := json.Unmarshal(rw.Body.Bytes(), report); err != nil {
+			t.Fatal(err)
+		}
+		if report.TLSCertificateExpiry == nil || !report.TLSCertificateExpiry.Equal(cert.cert.NotAfter) {
+			t.Fatalf("%s tlsCertificateExpiry = %v, want %v", path, report.TLSCertificateExpiry, cert.cert.NotAfter)
+		}
+	}
+}
@@ -1,9 +1,15 @@
 package confserver
 
 import (
-	"github.com/gin-gonic/gin"
+	"errors"
 	"net/http"
+	"regexp"
+	"strconv"
 	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
 )
 
 const (
@@ -48,32 +54,233 @@ var (
 	}
 )
 
-func DefaultCORS() gin.HandlerFunc {
-	return func(c *gin.Context) {
-		c.Header("Access-Control-Allow-Origin", "*")
-		c.Header("Access-Control-Allow-Methods", strings.Join(defaultCorsMethods, ","))
-		c.Header("Access-Control-Allow-Headers", strings.Join(defaultCorsHeaders, ","))
-		c.Header("Access-Control-Expose-Headers", "Content-Length")
-		c.Header("Access-Control-Allow-Credentials", "true")
-		if c.Request.Method == "OPTIONS" {
-			c.AbortWithStatus(http.StatusNoContent)
-			return
+// CORS 跨域配置
+type CORS struct {
+	// AllowOrigins 允许的 Origin, 支持 * / https://*.example.com 通配 / ^https://.*$ 正则
+	AllowOrigins []string `env:""`
+	// AllowMethods 为 * 时允许任意请求方法
+	AllowMethods []string `env:""`
+	// AllowHeaders 为 * 时允许任意请求头
+	AllowHeaders  []string `env:""`
+	ExposeHeaders []string `env:""`
+	// MaxAge 预检请求缓存时间
+	MaxAge Duration `env:""`
+	// AllowCredentials 需明确配置 AllowOrigins, 不能与 AllowOrigins * 或 ExposeHeaders * 同时使用
+	AllowCredentials bool `env:""`
+}
+
+func (c *CORS) SetDefaults() {
+	if len(c.AllowOrigins) == 0 {
+		c.AllowOrigins = []string{"*"}
+	}
+	if len(c.AllowMethods) == 0 {
+		c.AllowMethods = defaultCorsMethods
+	}
+	if len(c.AllowHeaders) == 0 {
+		c.AllowHeaders = defaultCorsHeaders
+	}
+	if len(c.ExposeHeaders) == 0 {
+		c.ExposeHeaders = []string{corsContentLengthHeader}
+	}
+}
+
+// validate 携带凭证时浏览器不接受 *, 回显任意 Origin 会放行所有站点的凭证请求
+func (c *CORS) validate() error {
+	if !c.AllowCredentials {
+		return nil
+	}
+	for _, origin := range c.AllowOrigins {
+		if strings.TrimSpace(origin) == "*" {
+			return errors.New("cors: AllowCredentials can not be used with AllowOrigins *, list the allowed origins explicitly")
 		}
-		c.Next()
 	}
+	for _, header := range c.ExposeHeaders {
+		if strings.TrimSpace(header) == "*" {
+			return errors.New("cors: ExposeHeaders * is not a wildcard when AllowCredentials is enabled, list the exposed headers explicitly")
+		}
+	}
+	return nil
 }
 
-func AllowAllCors() gin.HandlerFunc {
-	return func(c *gin.Context) {
-		c.Header("Access-Control-Allow-Origin", "*")
-		c.Header("Access-Control-Allow-Methods", "*")
-		c.Header("Access-Control-Allow-Headers", "*")
-		c.Header("Access-Control-Expose-Headers", "*")
-		c.Header("Access-Control-Allow-Credentials", "true")
-		if c.Request.Method == "OPTIONS" {
-			c.AbortWithStatus(http.StatusNoContent)
-			return
+type corsPolicy struct {
+	CORS
+
+	allowAllOrigins bool
+	allowAllMethods bool
+	allowAllHeaders bool
+	origins         map[string]bool
+	originPatterns  []*regexp.Regexp
+	methods         map[string]bool
+	headers         map[string]bool
+}
+
+func newCorsPolicy(conf CORS) *corsPolicy {
+	p := &corsPolicy{
+		CORS:    conf,
+		origins: map[string]bool{},
+		methods: map[string]bool{},
+		headers: map[string]bool{},
+	}
+
+	for _, origin := range conf.AllowOrigins {
+		origin = strings.TrimSpace(origin)
+		switch {
+		case origin == "*":
+			p.allowAllOrigins = true
+		case strings.HasPrefix(origin, "^"):
+			pattern, err := regexp.Compile(origin)
+			if err != nil {
+				// 无效的正则不放行任何 Origin
+				logrus.WithField("tag", "cors").WithError(err).Errorf("skip invalid allow origin pattern %q", origin)
+				continue
+			}
+			p.originPatterns = append(p.originPatterns, pattern)
+		case strings.Contains(origin, "*"):
+			p.originPatterns = append(p.originPatterns, wildcardToRegexp(strings.ToLower(origin)))
+		default:
+			p.origins[strings.ToLower(origin)] = true
+		}
+	}
+
+	for _, method := range conf.AllowMethods {
+		if method == "*" {
+			p.allowAllMethods = true
+		}
+		p.methods[strings.ToUpper(strings.TrimSpace(method))] = true
+	}
+
+	for _, header := range conf.AllowHeaders {
+		if header == "*" {
+			p.allowAllHeaders = true
+		}
+		p.headers[http.CanonicalHeaderKey(strings.TrimSpace(header))] = true
+	}
+
+	return p
+}
+
+func wildcardToRegexp(pattern string) *regexp.Regexp {
+	parts := strings.Split(pattern, "*")
+	for i := range parts {
+		parts[i] = regexp.QuoteMeta(parts[i])
+	}
+	return regexp.MustCompile("^" + strings.Join(parts, "[^/]*") + "$")
+}
+
+func (p *corsPolicy) originAllowed(origin string) bool {
+	if p.allowAllOrigins {
+		return true
+	}
+	origin = strings.ToLower(origin)
+	if p.origins[origin] {
+		return true
+	}
+	for _, pattern := range p.originPatterns {
+		if pattern.MatchString(origin) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *corsPolicy) methodAllowed(method string) bool {
+	return p.allowAllMethods || p.methods[strings.ToUpper(method)]
+}
+
+func (p *corsPolicy) headersAllowed(requestHeaders string) bool {
+	if p.allowAllHeaders {
+		return true
+	}
+	for _, header := range strings.Split(requestHeaders, ",") {
+		header = strings.TrimSpace(header)
+		if header != "" && !p.headers[http.CanonicalHeaderKey(header)] {
+			return false
+		}
+	}
+	return true
+}
+
+func (p *corsPolicy) handle(c *gin.Context) {
+	c.Writer.Header().Add(corsVaryHeader, corsOriginHeader)
+
+	origin := c.GetHeader(corsOriginHeader)
+	// not a cors request
+	if origin == "" {
+		c.Next()
+		return
+	}
+
+	preflight := c.Request.Method == http.MethodOptions && c.GetHeader(corsRequestMethodHeader) != ""
+	requestMethod := c.GetHeader(corsRequestMethodHeader)
+	requestHeaders := c.GetHeader(corsRequestHeadersHeader)
+
+	if preflight {
+		c.Writer.Header().Add(corsVaryHeader, corsRequestMethodHeader)
+		c.Writer.Header().Add(corsVaryHeader, corsRequestHeadersHeader)
+	}
+
+	if !p.originAllowed(origin) || (preflight && (!p.methodAllowed(requestMethod) || !p.headersAllowed(requestHeaders))) {
+		c.AbortWithStatus(http.StatusForbidden)
+		return
+	}
+
+	// AllowCredentials 不能与 * 同时配置, 仅明确配置的 Origin 回显
+	if p.allowAllOrigins {
+		c.Header(corsAllowOriginHeader, "*")
+	} else {
+		c.Header(corsAllowOriginHeader, origin)
+	}
+	if p.AllowCredentials {
+		c.Header(corsAllowCredentialsHeader, "true")
+	}
+
+	if !preflight {
+		if len(p.ExposeHeaders) > 0 {
+			c.Header(corsExposeHeadersHeader, strings.Join(p.ExposeHeaders, ","))
 		}
 		c.Next()
+		return
+	}
+
+	if p.allowAllMethods {
+		c.Header(corsAllowMethodsHeader, requestMethod)
+	} else {
+		c.Header(corsAllowMethodsHeader, strings.Join(p.AllowMethods, ","))
+	}
+	if p.allowAllHeaders {
+		if requestHeaders != "" {
+			c.Header(corsAllowHeadersHeader, requestHeaders)
+		}
+	} else {
+		c.Header(corsAllowHeadersHeader, strings.Join(p.AllowHeaders, ","))
 	}
+	if p.MaxAge > 0 {
+		c.Header(corsMaxAgeHeader, strconv.FormatInt(int64(time.Duration(p.MaxAge)/time.Second), 10))
+	}
+
+	c.AbortWithStatus(http.StatusNoContent)
+}
+
+// NewCORS 按配置处理跨域请求, 不允许的 Origin 返回 403, 配置无效时 panic
+func NewCORS(conf CORS) gin.HandlerFunc {
+	conf.SetDefaults()
+	if err := conf.validate(); err != nil {
+		panic(err)
+	}
+	return newCorsPolicy(conf).handle
+}
+
+// DefaultCORS 允许任意 Origin, 不携带凭证
+func DefaultCORS() gin.HandlerFunc {
+	return NewCORS(CORS{})
+}
+
+// AllowAllCors 允许任意 Origin / 方法 / 请求头, 不携带凭证
+func AllowAllCors() gin.HandlerFunc {
+	return NewCORS(CORS{
+		AllowOrigins:  []string{"*"},
+		AllowMethods:  []string{"*"},
+		AllowHeaders:  []string{"*"},
+		ExposeHeaders: []string{"*"},
+	})
 }
@@ -0,0 +1,216 @@
+package confserver
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestCorsOriginAllowed(t *testing.T) {
+	cases := []struct {
+		name    string
+		origins []string
+		origin  string
+		allowed bool
+	}{
+		{name: "all", origins: []string{"*"}, origin: "https://a.example.com", allowed: true},
+		{name: "exact", origins: []string{"https://example.com"}, origin: "https://example.com", allowed: true},
+		{name: "exact case insensitive", origins: []string{"https://Example.com"}, origin: "https://EXAMPLE.com", allowed: true},
+		{name: "exact mismatch", origins: []string{"https://example.com"}, origin: "https://example.org", allowed: false},
+		{name: "wildcard subdomain", origins: []string{"https://*.example.com"}, origin: "https://a.example.com", allowed: true},
+		{name: "wildcard nested subdomain", origins: []string{"https://*.example.com"}, origin: "https://a.b.example.com", allowed: true},
+		{name: "wildcard apex", origins: []string{"https://*.example.com"}, origin: "https://example.com", allowed: false},
+		{name: "wildcard scheme mismatch", origins: []string{"https://*.example.com"}, origin: "http://a.example.com", allowed: false},
+		{name: "wildcard suffix attack", origins: []string{"https://*.example.com"}, origin: "https://a.example.com.evil.io", allowed: false},
+		{name: "wildcard dot is literal", origins: []string{"https://*.example.com"}, origin: "https://aXexample.com", allowed: false},
+		{name: "wildcard port", origins: []string{"http://localhost:*"}, origin: "http://localhost:8080", allowed: true},
+		{name: "regexp", origins: []string{`^https://[a-z]+\.example\.com$`}, origin: "https://app.example.com", allowed: true},
+		{name: "regexp mismatch", origins: []string{`^https://[a-z]+\.example\.com$`}, origin: "https://app1.example.com", allowed: false},
+		{name: "invalid regexp is skipped", origins: []string{`^https://(`, "https://example.com"}, origin: "https://example.com", allowed: true},
+		{name: "invalid regexp allows nothing", origins: []string{`^https://(`}, origin: "https://(", allowed: false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			conf := CORS{AllowOrigins: c.origins}
+			conf.SetDefaults()
+			if got := newCorsPolicy(conf).originAllowed(c.origin); got != c.allowed {
+				t.Fatalf("originAllowed(%q) = %v, want %v", c.origin, got, c.allowed)
+			}
+		})
+	}
+}
+
+func TestCorsHandler(t *testing.T) {
+	cases := []struct {
+		name        string
+		conf        CORS
+		method      string
+		headers     map[string]string
+		status      int
+		allowOrigin string
+		// allowCredentials 期望的 Access-Control-Allow-Credentials
+		allowCredentials string
+	}{
+		{
+			name:        "simple request",
+			conf:        CORS{AllowOrigins: []string{"https://example.com"}},
+			method:      http.MethodGet,
+			headers:     map[string]string{"Origin": "https://example.com"},
+			status:      http.StatusOK,
+			allowOrigin: "https://example.com",
+		},
+		{
+			name:   "forbidden origin",
+			conf:   CORS{AllowOrigins: []string{"https://example.com"}},
+			method: http.MethodGet,
+			headers: map[string]string{
+				"Origin": "https://evil.io",
+			},
+			status: http.StatusForbidden,
+		},
+		{
+			name:    "not a cors request",
+			conf:    CORS{AllowOrigins: []string{"https://example.com"}},
+			method:  http.MethodGet,
+			headers: map[string]string{},
+			status:  http.StatusOK,
+		},
+		{
+			name:   "preflight",
+			conf:   CORS{AllowOrigins: []string{"https://*.example.com"}},
+			method: http.MethodOptions,
+			headers: map[string]string{
+				"Origin":                         "https://a.example.com",
+				"Access-Control-Request-Method":  http.MethodPut,
+				"Access-Control-Request-Headers": "content-type",
+			},
+			status:      http.StatusNoContent,
+			allowOrigin: "https://a.example.com",
+		},
+		{
+			name:   "preflight method not allowed",
+			conf:   CORS{AllowOrigins: []string{"*"}, AllowMethods: []string{"GET"}},
+			method: http.MethodOptions,
+			headers: map[string]string{
+				"Origin":                        "https://a.example.com",
+				"Access-Control-Request-Method": http.MethodDelete,
+			},
+			status: http.StatusForbidden,
+		},
+		{
+			name:   "preflight header not allowed",
+			conf:   CORS{AllowOrigins: []string{"*"}},
+			method: http.MethodOptions,
+			headers: map[string]string{
+				"Origin":                         "https://a.example.com",
+				"Access-Control-Request-Method":  http.MethodGet,
+				"Access-Control-Request-Headers": "X-Custom",
+			},
+			status: http.StatusForbidden,
+		},
+		{
+			name:             "credentials echo listed origin",
+			conf:             CORS{AllowOrigins: []string{"https://*.example.com"}, AllowCredentials: true},
+			method:           http.MethodGet,
+			headers:          map[string]string{"Origin": "https://a.example.com"},
+			status:           http.StatusOK,
+			allowOrigin:      "https://a.example.com",
+			allowCredentials: "true",
+		},
+		{
+			name:        "allow all without credentials",
+			conf:        CORS{AllowOrigins: []string{"*"}, AllowMethods: []string{"*"}, AllowHeaders: []string{"*"}, ExposeHeaders: []string{"*"}},
+			method:      http.MethodGet,
+			headers:     map[string]string{"Origin": "https://evil.io"},
+			status:      http.StatusOK,
+			allowOrigin: "*",
+		},
+		{
+			name:        "all origins without credentials",
+			conf:        CORS{AllowOrigins: []string{"*"}},
+			method:      http.MethodGet,
+			headers:     map[string]string{"Origin": "https://a.example.com"},
+			status:      http.StatusOK,
+			allowOrigin: "*",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			r := gin.New()
+			r.Use(NewCORS(c.conf))
+			r.Any("/", func(ctx *gin.Context) {
+				ctx.Status(http.StatusOK)
+			})
+
+			req := httptest.NewRequest(c.method, "/", nil)
+			for k, v := range c.headers {
+				req.Header.Set(k, v)
+			}
+			rw := httptest.NewRecorder()
+			r.ServeHTTP(rw, req)
+
+			if rw.Code != c.status {
+				t.Fatalf("status = %d, want %d", rw.Code, c.status)
+			}
+			if got := rw.Header().Get("Access-Control-Allow-Origin"); got != c.allowOrigin {
+				t.Fatalf("Access-Control-Allow-Origin = %q, want %q", got, c.allowOrigin)
+			}
+			if got := rw.Header().Get("Access-Control-Allow-Credentials"); got != c.allowCredentials {
+				t.Fatalf("Access-Control-Allow-Credentials = %q, want %q", got, c.allowCredentials)
+			}
+		})
+	}
+}
+
+func TestCorsPresetsWithoutCredentials(t *testing.T) {
+	for name, handler := range map[string]gin.HandlerFunc{
+		"DefaultCORS":  DefaultCORS(),
+		"AllowAllCors": AllowAllCors(),
+	} {
+		t.Run(name, func(t *testing.T) {
+			r := gin.New()
+			r.Use(handler)
+			r.GET("/", func(ctx *gin.Context) {
+				ctx.Status(http.StatusOK)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("Origin", "https://evil.io")
+			rw := httptest.NewRecorder()
+			r.ServeHTTP(rw, req)
+
+			if got := rw.Header().Get("Access-Control-Allow-Origin"); got != "*" {
+				t.Fatalf("Access-Control-Allow-Origin = %q, want *", got)
+			}
+			if got := rw.Header().Get("Access-Control-Allow-Credentials"); got != "" {
+				t.Fatalf("Access-Control-Allow-Credentials = %q, want empty", got)
+			}
+		})
+	}
+}
+
+func TestNewCORSInvalidCredentials(t *testing.T) {
+	cases := []struct {
+		name string
+		conf CORS
+	}{
+		{name: "default origins", conf: CORS{AllowCredentials: true}},
+		{name: "all origins", conf: CORS{AllowOrigins: []string{"https://example.com", "*"}, AllowCredentials: true}},
+		{name: "all expose headers", conf: CORS{AllowOrigins: []string{"https://example.com"}, ExposeHeaders: []string{"*"}, AllowCredentials: true}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Fatal("expect panic")
+				}
+			}()
+			NewCORS(c.conf)
+		})
+	}
+}
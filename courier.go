@@ -0,0 +1,114 @@
+package confserver
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-courier/courier"
+	"github.com/go-courier/httptransport"
+	contextx "github.com/go-courier/x/context"
+	"github.com/kunlun-qilian/confx"
+	"github.com/sirupsen/logrus"
+)
+
+// MountCourierRouter 将 go-courier Router 下的 operator 链注册为 SvcRootRouter() 下的 gin 路由,
+// 与 Bind 共用 request transformer, 并经过 Server 的日志及 trace 中间件
+func (s *Server) MountCourierRouter(router *courier.Router) error {
+	routes := router.Routes()
+	if len(routes) == 0 {
+		return fmt.Errorf("need to register Operator to Router %#v before mount", router)
+	}
+
+	serviceMeta := &httptransport.ServiceMeta{Name: confx.Config.ServiceName()}
+	serviceMeta.SetDefaults()
+
+	routeMetas := make([]*httptransport.HttpRouteMeta, len(routes))
+	for i := range routes {
+		routeMetas[i] = httptransport.NewHttpRouteMeta(routes[i])
+	}
+	sort.Slice(routeMetas, func(i, j int) bool {
+		return routeMetas[i].Key() < routeMetas[j].Key()
+	})
+
+	root := s.SvcRootRouter()
+	for i := range routeMetas {
+		if err := mountCourierRoute(root, serviceMeta, routeMetas[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// multipartResponseWriter 读取 body 超限时 以 413 替换 go-courier 写出的响应, 与 Bind 一致
+type multipartResponseWriter struct {
+	http.ResponseWriter
+	c        *gin.Context
+	upload   *multipartState
+	replaced bool
+}
+
+func (w *multipartResponseWriter) WriteHeader(statusCode int) {
+	if w.replace() {
+		return
+	}
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (w *multipartResponseWriter) Write(data []byte) (int, error) {
+	if w.replace() {
+		return len(data), nil
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *multipartResponseWriter) replace() bool {
+	if w.replaced || w.c.Writer.Written() {
+		return w.replaced
+	}
+	if tooLarge := w.upload.tooLargeErr(); tooLarge != nil {
+		w.replaced = true
+		WriteError(w.c, requestEntityTooLarge(tooLarge))
+	}
+	return w.replaced
+}
+
+func mountCourierRoute(root *gin.RouterGroup, serviceMeta *httptransport.ServiceMeta, routeMeta *httptransport.HttpRouteMeta) (err error) {
+	// NewHttpRouteHandler 及 gin 路由冲突均以 panic 报错
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("mount courier route `%s %s` failed: %v", routeMeta.Method(), routeMeta.Path(), r)
+		}
+	}()
+
+	handler := httptransport.NewHttpRouteHandler(serviceMeta, routeMeta, rtMgr)
+	operationID := routeMeta.OperatorFactoryWithRouteMetas[len(routeMeta.OperatorFactoryWithRouteMetas)-1].ID
+
+	root.Handle(routeMeta.Method(), routeMeta.Path(), func(c *gin.Context) {
+		ctx := httptransport.ContextWithOperationID(c.Request.Context(), operationID)
+
+		var rw http.ResponseWriter = c.Writer
+		if isMultipart(c.Request) {
+			upload, err := limitMultipartBody(c)
+			if err != nil {
+				WriteError(c, err)
+				return
+			}
+			ctx = contextx.WithValue(ctx, contextKeyMultipartState{}, upload)
+			rw = &multipartResponseWriter{ResponseWriter: c.Writer, c: c, upload: upload}
+		}
+
+		c.Request = c.Request.WithContext(ctx)
+		handler.ServeHTTP(rw, contextWithPathParams(c))
+	})
+
+	logrus.WithFields(logrus.Fields{
+		"tag":       "courier",
+		"method":    routeMeta.Method(),
+		"path":      routeMeta.Path(),
+		"operation": operationID,
+	}).Debug("mount courier route")
+
+	return nil
+}
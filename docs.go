@@ -0,0 +1,103 @@
+package confserver
+
+import (
+	"fmt"
+	"html"
+	"io/fs"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kunlun-qilian/confx"
+)
+
+const (
+	DocsUISwagger = "swagger"
+	DocsUIRedoc   = "redoc"
+)
+
+// 文档页面依赖的静态资源, DocsAssetsURL 或 SetDocsAssetsFS 中按以下文件名提供
+const (
+	docsAssetSwaggerCSS = "swagger-ui.css"
+	docsAssetSwaggerJS  = "swagger-ui-bundle.js"
+	docsAssetRedocJS    = "redoc.standalone.js"
+)
+
+// 未配置时从 CDN 加载
+var docsCDNAssets = map[string]string{
+	docsAssetSwaggerCSS: "https://cdn.jsdelivr.net/npm/swagger-ui-dist@5/swagger-ui.css",
+	docsAssetSwaggerJS:  "https://cdn.jsdelivr.net/npm/swagger-ui-dist@5/swagger-ui-bundle.js",
+	docsAssetRedocJS:    "https://cdn.jsdelivr.net/npm/redoc@2/bundles/redoc.standalone.js",
+}
+
+const swaggerUIPage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8" />
+  <title>%s</title>
+  <link rel="stylesheet" href="%s" />
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="%s"></script>
+  <script>
+    window.ui = SwaggerUIBundle({ url: %q, dom_id: "#swagger-ui", deepLinking: true });
+  </script>
+</body>
+</html>
+`
+
+const redocPage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8" />
+  <title>%s</title>
+</head>
+<body>
+  <redoc spec-url=%q></redoc>
+  <script src="%s"></script>
+</body>
+</html>
+`
+
+// DocsHandler 基于服务自身 OpenAPI 路由的文档页面, ?ui=redoc 可切换
+func (s *Server) DocsHandler(ctx *gin.Context) {
+	ui := strings.ToLower(ctx.Query("ui"))
+	if ui == "" {
+		ui = strings.ToLower(s.DocsUI)
+	}
+
+	// relative to the docs route so it keeps working behind a path-rewriting gateway
+	specURL := "openapi"
+	title := html.EscapeString(confx.Config.ServiceName())
+
+	var page string
+	if ui == DocsUIRedoc {
+		page = fmt.Sprintf(redocPage, title, specURL, s.docsAssetURL(docsAssetRedocJS))
+	} else {
+		page = fmt.Sprintf(swaggerUIPage, title, s.docsAssetURL(docsAssetSwaggerCSS), s.docsAssetURL(docsAssetSwaggerJS), specURL)
+	}
+	ctx.Data(http.StatusOK, "text/html; charset=utf-8", []byte(page))
+}
+
+// SetDocsAssetsFS 从 fsys (如 go:embed) 提供文档页面的静态资源, 用于无法访问 CDN 的环境, 需在 Init 前调用.
+// fsys 根目录需包含 swagger-ui.css, swagger-ui-bundle.js 及 redoc.standalone.js
+func (s *Server) SetDocsAssetsFS(fsys fs.FS) {
+	s.docsAssetsFS = fsys
+}
+
+// docsAssetURL 优先使用 DocsAssetsURL, 其次为 SetDocsAssetsFS 提供的 {RootPath}/docs/assets
+func (s *Server) docsAssetURL(name string) string {
+	switch {
+	case s.DocsAssetsURL != "":
+		return html.EscapeString(strings.TrimRight(s.DocsAssetsURL, "/") + "/" + name)
+	case s.docsAssetsFS != nil:
+		// relative to the docs route, same as the spec url
+		return "docs/assets/" + name
+	}
+	return docsCDNAssets[name]
+}
+
+func (s *Server) docsEnabled() bool {
+	return s.EnableDocs || strings.ToLower(s.Mode) == "debug"
+}
@@ -0,0 +1,75 @@
+package confserver
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestDocsAssets(t *testing.T) {
+	cases := []struct {
+		name   string
+		server *Server
+		assets fstest.MapFS
+		ui     string
+		want   []string
+	}{
+		{
+			name:   "cdn",
+			server: &Server{EnableDocs: true},
+			want:   []string{"https://cdn.jsdelivr.net/npm/swagger-ui-dist@5/swagger-ui-bundle.js"},
+		},
+		{
+			name:   "assets url",
+			server: &Server{EnableDocs: true, DocsAssetsURL: "https://mirror.internal/docs/"},
+			want:   []string{`href="https://mirror.internal/docs/swagger-ui.css"`, `src="https://mirror.internal/docs/swagger-ui-bundle.js"`},
+		},
+		{
+			name:   "assets url redoc",
+			server: &Server{EnableDocs: true, DocsAssetsURL: "https://mirror.internal/docs"},
+			ui:     DocsUIRedoc,
+			want:   []string{`src="https://mirror.internal/docs/redoc.standalone.js"`},
+		},
+		{
+			name:   "assets fs",
+			server: &Server{EnableDocs: true},
+			assets: fstest.MapFS{docsAssetSwaggerJS: {Data: []byte("swagger")}},
+			want:   []string{`src="docs/assets/swagger-ui-bundle.js"`},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			s := c.server
+			if c.assets != nil {
+				s.SetDocsAssetsFS(c.assets)
+			}
+			s.SetDefaults()
+			s.Init()
+
+			rw := httptest.NewRecorder()
+			s.Engine().ServeHTTP(rw, httptest.NewRequest(http.MethodGet, joinRoutePath(s.SvcRootRouter().BasePath(), "/docs?ui="+c.ui), nil))
+			if rw.Code != http.StatusOK {
+				t.Fatalf("status = %d", rw.Code)
+			}
+			for _, want := range c.want {
+				if !strings.Contains(rw.Body.String(), want) {
+					t.Fatalf("page missing %s:\n%s", want, rw.Body)
+				}
+			}
+			if strings.Contains(rw.Body.String(), "cdn.jsdelivr.net") != (c.name == "cdn") {
+				t.Fatalf("unexpected cdn reference:\n%s", rw.Body)
+			}
+
+			if c.assets != nil {
+				rw := httptest.NewRecorder()
+				s.Engine().ServeHTTP(rw, httptest.NewRequest(http.MethodGet, joinRoutePath(s.SvcRootRouter().BasePath(), "/docs/assets/"+docsAssetSwaggerJS), nil))
+				if rw.Code != http.StatusOK || rw.Body.String() != "swagger" {
+					t.Fatalf("asset = %d %s", rw.Code, rw.Body)
+				}
+			}
+		})
+	}
+}
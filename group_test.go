@@ -0,0 +1,120 @@
+package confserver
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGroup(t *testing.T) {
+	errA := errors.New("a failed")
+	errB := errors.New("b failed")
+	errNotCancelled := errors.New("not cancelled")
+
+	// waitCancel 等待 ctx 取消后返回 err, 超时视为未被取消
+	waitCancel := func(err error) func(ctx context.Context) error {
+		return func(ctx context.Context) error {
+			select {
+			case <-ctx.Done():
+				return err
+			case <-time.After(2 * time.Second):
+				return errNotCancelled
+			}
+		}
+	}
+
+	cases := []struct {
+		name    string
+		parent  func() (context.Context, context.CancelFunc)
+		workers []func(ctx context.Context) error
+		errs    []error
+		panic   interface{}
+	}{
+		{
+			name: "all succeed",
+			workers: []func(ctx context.Context) error{
+				func(ctx context.Context) error { return nil },
+				func(ctx context.Context) error { return nil },
+			},
+		},
+		{
+			name: "first error cancels the others",
+			workers: []func(ctx context.Context) error{
+				func(ctx context.Context) error { return errA },
+				waitCancel(nil),
+			},
+			errs: []error{errA},
+		},
+		{
+			name: "errors are joined",
+			workers: []func(ctx context.Context) error{
+				func(ctx context.Context) error { return errA },
+				waitCancel(errB),
+				waitCancel(context.Canceled),
+			},
+			errs: []error{errA, errB, context.Canceled},
+		},
+		{
+			name: "panic is recovered",
+			workers: []func(ctx context.Context) error{
+				func(ctx context.Context) error { panic("boom") },
+				waitCancel(nil),
+			},
+			panic: "boom",
+		},
+		{
+			name: "parent cancel",
+			parent: func() (context.Context, context.CancelFunc) {
+				return context.WithTimeout(context.Background(), 10*time.Millisecond)
+			},
+			workers: []func(ctx context.Context) error{
+				waitCancel(nil),
+				waitCancel(nil),
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			parent, cancel := context.Background(), context.CancelFunc(func() {})
+			if c.parent != nil {
+				parent, cancel = c.parent()
+			}
+			defer cancel()
+
+			g, ctx := newGroup(parent)
+			for _, w := range c.workers {
+				g.Go(ctx, w)
+			}
+			err := g.Wait()
+
+			if ctx.Err() == nil {
+				t.Fatal("ctx should be cancelled after Wait")
+			}
+			if errors.Is(err, errNotCancelled) {
+				t.Fatalf("worker not cancelled: %v", err)
+			}
+			for _, want := range c.errs {
+				if !errors.Is(err, want) {
+					t.Fatalf("err = %v, want %v joined", err, want)
+				}
+			}
+
+			if c.panic == nil {
+				if len(c.errs) == 0 && err != nil {
+					t.Fatalf("err = %v, want nil", err)
+				}
+				return
+			}
+			var panicErr *PanicError
+			if !errors.As(err, &panicErr) {
+				t.Fatalf("err = %v, want PanicError", err)
+			}
+			if panicErr.Value != c.panic || len(panicErr.Stack) == 0 || !strings.Contains(panicErr.Error(), "panic: boom") {
+				t.Fatalf("unexpected panic error %v", panicErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package confserver
+
+import (
+	"context"
+	"mime"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-courier/httptransport/httpx"
+	"github.com/go-courier/httptransport/transformers"
+	"github.com/go-courier/statuserror"
+	typesutil "github.com/go-courier/x/types"
+	"github.com/kunlun-qilian/confx"
+)
+
+// Handle 将 func(ctx, *Req) (Resp, error) 适配为 gin.HandlerFunc,
+// 请求通过 Bind 解析校验, 响应按 Accept 选择 transformer 输出, statuserror 映射为对应状态码
+func Handle[Req any, Resp any](fn func(ctx context.Context, req *Req) (Resp, error)) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		req := new(Req)
+		if err := Bind(c, req); err != nil {
+			WriteError(c, err)
+			return
+		}
+
+		resp, err := fn(c.Request.Context(), req)
+		if err != nil {
+			WriteError(c, err)
+			return
+		}
+		WriteResponse(c, resp)
+	}
+}
+
+// WriteResponse 按 go-courier 的规则输出 v, 支持 httpx.Response 及各类 ResponseWrapper
+func WriteResponse(c *gin.Context, v interface{}) {
+	if err := httpx.ResponseFrom(v).WriteTo(c.Writer, c.Request, resolveTransformer(c.GetHeader("Accept"))); err != nil {
+		if c.Writer.Written() {
+			_ = c.Error(err)
+			return
+		}
+		WriteError(c, err)
+	}
+}
+
+// WriteError 输出 statuserror 格式的错误, Bind 的校验错误统一为 400, 其他非 statuserror 按 500 处理
+func WriteError(c *gin.Context, err error) {
+	_ = c.Error(err)
+
+	statusErr := BindError(err)
+	if resp, ok := err.(*httpx.Response); ok {
+		if e, ok := statuserror.IsStatusErr(resp.Unwrap()); ok {
+			statusErr = e
+		}
+	}
+
+	writeStatusErr(c, statusErr.AppendSource(confx.Config.ServiceName()))
+}
+
+func resolveTransformer(accept string) func(response *httpx.Response) (httpx.Encode, error) {
+	return func(response *httpx.Response) (httpx.Encode, error) {
+		typ := typesutil.FromRType(reflect.TypeOf(response.Value))
+
+		if response.ContentType == "" {
+			for _, mediaType := range acceptedMediaTypes(accept) {
+				transformer, err := rtMgr.NewTransformer(context.Background(), typ, transformers.TransformerOption{
+					MIME: mediaType,
+				})
+				if err == nil {
+					return transformer.EncodeTo, nil
+				}
+			}
+		}
+
+		transformer, err := rtMgr.NewTransformer(context.Background(), typ, transformers.TransformerOption{
+			MIME: response.ContentType,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return transformer.EncodeTo, nil
+	}
+}
+
+// acceptedMediaTypes 按 q 值降序排列 Accept 中的 media type, 忽略通配
+func acceptedMediaTypes(accept string) []string {
+	type weighted struct {
+		mediaType string
+		q         float64
+	}
+
+	var list []weighted
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil || strings.Contains(mediaType, "*") {
+			continue
+		}
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if f, err := strconv.ParseFloat(v, 64); err == nil {
+				q = f
+			}
+		}
+		if q > 0 {
+			list = append(list, weighted{mediaType: mediaType, q: q})
+		}
+	}
+
+	sort.SliceStable(list, func(i, j int) bool {
+		return list[i].q > list[j].q
+	})
+
+	mediaTypes := make([]string, len(list))
+	for i := range list {
+		mediaTypes[i] = list[i].mediaType
+	}
+	return mediaTypes
+}
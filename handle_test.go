@@ -0,0 +1,183 @@
+package confserver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-courier/httptransport/httpx"
+	"github.com/go-courier/statuserror"
+)
+
+type handleReq struct {
+	Name string `name:"name" in:"query" validate:"@string[1,]"`
+}
+
+type handleResp struct {
+	Name string `json:"name" xml:"name"`
+}
+
+func echoName(ctx context.Context, req *handleReq) (*handleResp, error) {
+	return &handleResp{Name: req.Name}, nil
+}
+
+func TestHandle(t *testing.T) {
+	errInternal := errors.New("dial tcp 10.0.0.1:5432: connect: connection refused")
+
+	cases := []struct {
+		name        string
+		handler     gin.HandlerFunc
+		debug       bool
+		target      string
+		accept      string
+		status      int
+		contentType string
+		body        string
+		// key statuserror 的 key, 非空时按错误响应校验
+		key  string
+		desc string
+	}{
+		{
+			name:        "json by default",
+			handler:     Handle(echoName),
+			target:      "/?name=a",
+			status:      http.StatusOK,
+			contentType: "application/json",
+			body:        `{"name":"a"}`,
+		},
+		{
+			name:        "xml by accept",
+			handler:     Handle(echoName),
+			target:      "/?name=a",
+			accept:      "application/xml",
+			status:      http.StatusOK,
+			contentType: "application/xml",
+			body:        `<handleResp><name>a</name></handleResp>`,
+		},
+		{
+			name:        "accept by q",
+			handler:     Handle(echoName),
+			target:      "/?name=a",
+			accept:      "application/json;q=0.5, application/xml",
+			status:      http.StatusOK,
+			contentType: "application/xml",
+		},
+		{
+			name:        "unsupported accept falls back to json",
+			handler:     Handle(echoName),
+			target:      "/?name=a",
+			accept:      "application/unknown, */*",
+			status:      http.StatusOK,
+			contentType: "application/json",
+		},
+		{
+			name: "response status code",
+			handler: Handle(func(ctx context.Context, req *handleReq) (*httpx.Response, error) {
+				return &httpx.Response{Value: &handleResp{Name: req.Name}, StatusCode: http.StatusAccepted}, nil
+			}),
+			target: "/?name=a",
+			status: http.StatusAccepted,
+			body:   `{"name":"a"}`,
+		},
+		{
+			name:    "bind error",
+			handler: Handle(echoName),
+			target:  "/",
+			status:  http.StatusBadRequest,
+			key:     "BadRequest",
+		},
+		{
+			name: "status error",
+			handler: Handle(func(ctx context.Context, req *handleReq) (*handleResp, error) {
+				return nil, statuserror.Wrap(errors.New("user a"), http.StatusNotFound, "UserNotFound", "user not found", "user a")
+			}),
+			target: "/?name=a",
+			status: http.StatusNotFound,
+			key:    "UserNotFound",
+			desc:   "user a",
+		},
+		{
+			name: "internal error hides desc",
+			handler: Handle(func(ctx context.Context, req *handleReq) (*handleResp, error) {
+				return nil, errInternal
+			}),
+			target: "/?name=a",
+			status: http.StatusInternalServerError,
+			key:    "InternalServerError",
+		},
+		{
+			name: "internal error desc in debug mode",
+			handler: Handle(func(ctx context.Context, req *handleReq) (*handleResp, error) {
+				return nil, errInternal
+			}),
+			debug:  true,
+			target: "/?name=a",
+			status: http.StatusInternalServerError,
+			key:    "InternalServerError",
+			desc:   errInternal.Error(),
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if c.debug {
+				gin.SetMode(gin.DebugMode)
+				defer gin.SetMode(gin.TestMode)
+			}
+
+			r := gin.New()
+			r.GET("/", c.handler)
+
+			req := httptest.NewRequest(http.MethodGet, c.target, nil)
+			if c.accept != "" {
+				req.Header.Set("Accept", c.accept)
+			}
+			rw := httptest.NewRecorder()
+			r.ServeHTTP(rw, req)
+
+			if rw.Code != c.status {
+				t.Fatalf("status = %d, want %d, body: %s", rw.Code, c.status, rw.Body)
+			}
+			if c.contentType != "" && !strings.HasPrefix(rw.Header().Get("Content-Type"), c.contentType) {
+				t.Fatalf("Content-Type = %q, want %q", rw.Header().Get("Content-Type"), c.contentType)
+			}
+			if c.body != "" && strings.TrimSpace(rw.Body.String()) != c.body {
+				t.Fatalf("body = %s, want %s", rw.Body, c.body)
+			}
+
+			if c.key == "" {
+				return
+			}
+			statusErr := &statuserror.StatusErr{}
+			if err := json.Unmarshal(rw.Body.Bytes(), statusErr); err != nil {
+				t.Fatal(err)
+			}
+			if statusErr.Key != c.key || statusErr.Desc != c.desc {
+				t.Fatalf("key = %q desc = %q, want %q %q", statusErr.Key, statusErr.Desc, c.key, c.desc)
+			}
+			if len(statusErr.Sources) == 0 {
+				t.Fatal("source missing")
+			}
+		})
+	}
+}
+
+func TestWriteResponseEncodeError(t *testing.T) {
+	r := gin.New()
+	r.GET("/", func(c *gin.Context) {
+		// chan 无法编码, 未写出响应时按错误输出
+		WriteResponse(c, make(chan int))
+	})
+
+	rw := httptest.NewRecorder()
+	r.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if rw.Code != http.StatusInternalServerError {
+		t.Fatalf("status = %d, want 500, body: %s", rw.Code, rw.Body)
+	}
+}
@@ -0,0 +1,215 @@
+package confserver
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type Probe string
+
+const (
+	ProbeLiveness  Probe = "livez"
+	ProbeReadiness Probe = "readyz"
+	ProbeStartup   Probe = "startupz"
+)
+
+const defaultHealthCheckTimeout = 5 * time.Second
+
+type HealthCheckFunc func(ctx context.Context) error
+
+type HealthCheckOption func(c *healthCheck)
+
+// WithHealthCheckTimeout 单个检查的超时时间, 默认 5s
+func WithHealthCheckTimeout(timeout time.Duration) HealthCheckOption {
+	return func(c *healthCheck) {
+		c.timeout = timeout
+	}
+}
+
+// NonCritical 检查失败仅在报告中体现, 不影响探针状态码
+func NonCritical() HealthCheckOption {
+	return func(c *healthCheck) {
+		c.critical = false
+	}
+}
+
+// WithProbes 检查生效的探针, 默认 readyz 和 startupz
+func WithProbes(probes ...Probe) HealthCheckOption {
+	return func(c *healthCheck) {
+		c.probes = probes
+	}
+}
+
+type healthCheck struct {
+	name     string
+	check    HealthCheckFunc
+	timeout  time.Duration
+	critical bool
+	probes   []Probe
+}
+
+func (c *healthCheck) in(probe Probe) bool {
+	for _, p := range c.probes {
+		if p == probe {
+			return true
+		}
+	}
+	return false
+}
+
+type healthRegistry struct {
+	mu     sync.RWMutex
+	checks []*healthCheck
+}
+
+func (r *healthRegistry) register(c *healthCheck) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i := range r.checks {
+		if r.checks[i].name == c.name {
+			r.checks[i] = c
+			return
+		}
+	}
+	r.checks = append(r.checks, c)
+}
+
+func (r *healthRegistry) checksOf(probe Probe) []*healthCheck {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var checks []*healthCheck
+	for _, c := range r.checks {
+		if c.in(probe) {
+			checks = append(checks, c)
+		}
+	}
+	return checks
+}
+
+type HealthReport struct {
+	Status               string              `json:"status"`
+	TLSCertificateExpiry *time.Time          `json:"tlsCertificateExpiry,omitempty"`
+	Checks               []HealthCheckResult `json:"checks"`
+}
+
+type HealthCheckResult struct {
+	Name     string `json:"name"`
+	Status   string `json:"status"`
+	Critical bool   `json:"critical"`
+	Duration string `json:"duration"`
+	Error    string `json:"error,omitempty"`
+}
+
+const (
+	healthStatusOK   = "ok"
+	healthStatusFail = "fail"
+)
+
+// RegisterHealthCheck 注册命名的健康检查, 同名检查会被替换
+func (s *Server) RegisterHealthCheck(name string, check HealthCheckFunc, opts ...HealthCheckOption) {
+	c := &healthCheck{
+		name:     name,
+		check:    check,
+		timeout:  defaultHealthCheckTimeout,
+		critical: true,
+		probes:   []Probe{ProbeReadiness, ProbeStartup},
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	s.healthRegistry().register(c)
+}
+
+func (s *Server) healthRegistry() *healthRegistry {
+	s.healthOnce.Do(func() {
+		s.health = &healthRegistry{}
+	})
+	return s.health
+}
+
+// ProbeHandler 执行 probe 对应的检查, 关键检查失败时返回 503
+func (s *Server) ProbeHandler(probe Probe) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		report := s.runHealthChecks(c.Request.Context(), probe)
+
+		status := http.StatusOK
+		if report.Status != healthStatusOK {
+			status = http.StatusServiceUnavailable
+		}
+		c.JSON(status, report)
+	}
+}
+
+func (s *Server) runHealthChecks(ctx context.Context, probe Probe) *HealthReport {
+	checks := s.healthRegistry().checksOf(probe)
+
+	report := &HealthReport{
+		Status: healthStatusOK,
+		Checks: make([]HealthCheckResult, len(checks)),
+	}
+
+	if s.certs != nil {
+		notAfter := s.certs.NotAfter()
+		report.TLSCertificateExpiry = &notAfter
+	}
+
+	wg := &sync.WaitGroup{}
+	for i := range checks {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			report.Checks[i] = runHealthCheck(ctx, checks[i])
+		}(i)
+	}
+	wg.Wait()
+
+	for _, result := range report.Checks {
+		if result.Critical && result.Status != healthStatusOK {
+			report.Status = healthStatusFail
+		}
+	}
+	return report
+}
+
+func runHealthCheck(ctx context.Context, c *healthCheck) (result HealthCheckResult) {
+	result = HealthCheckResult{
+		Name:     c.name,
+		Status:   healthStatusOK,
+		Critical: c.critical,
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	startTime := time.Now()
+	errCh := make(chan error, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				errCh <- fmt.Errorf("panic: %v", r)
+			}
+		}()
+		errCh <- c.check(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-errCh:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	result.Duration = ReprOfDuration(time.Since(startTime))
+	if err != nil {
+		result.Status = healthStatusFail
+		result.Error = err.Error()
+	}
+	return
+}
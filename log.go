@@ -13,13 +13,31 @@ import (
 	trace2 "github.com/kunlun-qilian/confserver/pkg/trace"
 	"github.com/sirupsen/logrus"
 	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/propagation"
 	"go.opentelemetry.io/otel/trace"
 )
 
-func TraceHandler() gin.HandlerFunc {
+var skipTracePaths = map[string]bool{
+	"/healthz":     true,
+	"/livez":       true,
+	"/readyz":      true,
+	"/startupz":    true,
+	"/favicon.ico": true,
+}
+
+// TraceHandler skipPaths 为额外不记录 trace 的路由, 如 Server 注册的 metrics 及 routez
+func TraceHandler(skipPaths ...string) gin.HandlerFunc {
+	skip := make(map[string]bool, len(skipTracePaths)+len(skipPaths))
+	for p := range skipTracePaths {
+		skip[p] = true
+	}
+	for _, p := range skipPaths {
+		skip[p] = true
+	}
+
 	return func(c *gin.Context) {
-		if c.FullPath() == "/swagger/*any" || c.FullPath() == "/healthz" || c.FullPath() == "/favicon.ico" {
+		if skip[c.FullPath()] {
 			c.Next()
 			return
 		}
@@ -34,6 +52,15 @@ func TraceHandler() gin.HandlerFunc {
 			span.End()
 		}()
 
+		// mTLS client certificate
+		if cert := ClientCertificate(c); cert != nil {
+			span.TraceSpan().SetAttributes(
+				attribute.String("tls.client.subject", cert.Subject.String()),
+				attribute.String("tls.client.issuer", cert.Issuer.String()),
+				attribute.String("tls.client.serial_number", cert.SerialNumber.String()),
+			)
+		}
+
 		ctx = logr.WithLogger(ctx, conflogger.StdLogger())
 
 		// inject trace span
@@ -44,6 +71,26 @@ func TraceHandler() gin.HandlerFunc {
 	}
 }
 
+// HTTPMetricsHandler 记录 OTel http.server.request.duration, 需在 TraceHandler 之后以便关联 exemplar
+func HTTPMetricsHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		startTime := time.Now()
+
+		c.Next()
+
+		ctx := c.Request.Context()
+		if span := trace2.GetTraceSpanFromContext(ctx); span != nil {
+			ctx = span.Context()
+		}
+
+		scheme := "http"
+		if c.Request.TLS != nil {
+			scheme = "https"
+		}
+		trace2.RecordHTTPServerRequest(ctx, c.Request.Method, c.FullPath(), scheme, c.Writer.Status(), time.Since(startTime))
+	}
+}
+
 func LoggerHandler() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		startTime := time.Now()
@@ -0,0 +1,47 @@
+package confserver
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestTraceHandlerSkipPaths(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	prev := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	defer otel.SetTracerProvider(prev)
+
+	s := &Server{EnableMetrics: true, MetricsPath: "/internal/metrics", EnableRouteInventory: true}
+	s.SetDefaults()
+	s.Init()
+	s.Engine().GET("/metrics", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	cases := []struct {
+		path   string
+		traced bool
+	}{
+		{path: "/internal/metrics", traced: false},
+		{path: "/routez", traced: false},
+		{path: "/healthz", traced: false},
+		// 业务自己的 /metrics 路由需要 trace
+		{path: "/metrics", traced: true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.path, func(t *testing.T) {
+			before := len(recorder.Ended())
+			s.Engine().ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, c.path, nil))
+			if traced := len(recorder.Ended()) > before; traced != c.traced {
+				t.Fatalf("traced = %v, want %v", traced, c.traced)
+			}
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package confserver
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// 未匹配到路由的请求统一使用该 route 标签, 避免按原始 path 打标签
+const unmatchedRoute = "unmatched"
+
+// serverMetrics 按 method / gin FullPath / 状态码分类统计的 RED 指标
+type serverMetrics struct {
+	registry *prometheus.Registry
+	requests *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+	inFlight *prometheus.GaugeVec
+}
+
+func newServerMetrics() *serverMetrics {
+	m := &serverMetrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "http_requests_total",
+			Help: "Total number of HTTP requests handled.",
+		}, []string{"method", "route", "status_class"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "http_request_duration_seconds",
+			Help:    "HTTP request latency in seconds.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"method", "route", "status_class"}),
+		inFlight: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "http_requests_in_flight",
+			Help: "Number of HTTP requests currently being handled.",
+		}, []string{"method", "route"}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.requests,
+		m.duration,
+		m.inFlight,
+	)
+	return m
+}
+
+// MetricsRegistry Prometheus registry, 可注册业务自定义的指标, EnableMetrics 关闭时为 nil
+func (s *Server) MetricsRegistry() *prometheus.Registry {
+	if s.metrics == nil {
+		return nil
+	}
+	return s.metrics.registry
+}
+
+// MetricsMiddleware 记录请求数, 耗时及处理中的请求数
+func (s *Server) MetricsMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		route := c.FullPath()
+		if route == "" {
+			route = unmatchedRoute
+		}
+		method := c.Request.Method
+
+		inFlight := s.metrics.inFlight.WithLabelValues(method, route)
+		inFlight.Inc()
+		defer inFlight.Dec()
+
+		startTime := time.Now()
+		c.Next()
+
+		statusClass := statusClassOf(c.Writer.Status())
+		s.metrics.requests.WithLabelValues(method, route, statusClass).Inc()
+		s.metrics.duration.WithLabelValues(method, route, statusClass).Observe(time.Since(startTime).Seconds())
+	}
+}
+
+// MetricsHandler 以 Prometheus text 格式输出指标
+func (s *Server) MetricsHandler() http.Handler {
+	return promhttp.HandlerFor(s.metrics.registry, promhttp.HandlerOpts{})
+}
+
+// serveMetrics MetricsPort 单独监听时的 admin server
+func (s *Server) serveMetrics(ctx context.Context) error {
+	mux := http.NewServeMux()
+	mux.Handle(s.MetricsPath, s.MetricsHandler())
+
+	return s.listenAndServe(ctx, &http.Server{
+		Addr:    fmt.Sprintf(":%d", s.MetricsPort),
+		Handler: mux,
+	})
+}
+
+// statusClassOf 200 => 2xx
+func statusClassOf(status int) string {
+	return strconv.Itoa(status/100) + "xx"
+}
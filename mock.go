@@ -0,0 +1,286 @@
+package confserver
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// 合成示例时 schema 的最大递归深度, 防止循环引用
+const maxMockSchemaDepth = 8
+
+// registerMockRoutes 为 OpenAPISpec 中尚未注册 handler 的 operation 注册 mock 路由
+func (s *Server) registerMockRoutes() {
+	doc, err := s.openapi.Doc()
+	if err != nil {
+		logrus.WithField("tag", "mock").WithError(err).Error("load openapi spec failed")
+		return
+	}
+	router, err := doc.Router()
+	if err != nil {
+		return
+	}
+
+	keys := make([]string, 0, len(router.routes))
+	for key := range router.routes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	registered := map[string]bool{}
+	for _, route := range s.r.Routes() {
+		registered[routeKey(route.Method, route.Path)] = true
+	}
+
+	for _, key := range keys {
+		if registered[key] {
+			continue
+		}
+		method, path, _ := strings.Cut(key, " ")
+		s.registerMockRoute(method, path, router.routes[key])
+	}
+}
+
+func (s *Server) registerMockRoute(method string, path string, route *routers.Route) {
+	// gin panics on conflicting wildcards, skip those operations
+	defer func() {
+		if r := recover(); r != nil {
+			logrus.WithFields(logrus.Fields{
+				"tag":    "mock",
+				"method": method,
+				"path":   path,
+			}).Warnf("skip mock route: %v", r)
+		}
+	}()
+	s.r.Handle(method, path, MockHandler(route.Operation))
+}
+
+// MockHandler 按 operation 声明的响应返回示例或合成数据,
+// 支持 Prefer: code=404 / example=name 选择响应
+func MockHandler(operation *openapi3.Operation) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		prefer := parsePrefer(c.GetHeader("Prefer"))
+
+		status, response := mockResponse(operation, prefer["code"])
+		if prefer["code"] != "" {
+			c.Header("Preference-Applied", "code="+strconv.Itoa(status))
+		}
+
+		if response == nil || len(response.Content) == 0 {
+			c.Status(status)
+			return
+		}
+
+		mediaType, contentType := mockMediaType(response.Content)
+		if mediaType == nil {
+			c.Status(status)
+			return
+		}
+		for name, header := range response.Headers {
+			if header.Value != nil && header.Value.Schema != nil {
+				c.Header(name, fmt.Sprint(mockExample(header.Value.Schema, 0)))
+			}
+		}
+
+		body := mockMediaTypeExample(mediaType, prefer["example"])
+		if strings.Contains(contentType, "json") {
+			c.JSON(status, body)
+			return
+		}
+		c.Data(status, contentType, []byte(fmt.Sprint(body)))
+	}
+}
+
+// parsePrefer Prefer: code=404, example=notFound
+func parsePrefer(header string) map[string]string {
+	prefer := map[string]string{}
+	for _, part := range strings.FieldsFunc(header, func(r rune) bool { return r == ',' || r == ';' }) {
+		k, v, _ := strings.Cut(strings.TrimSpace(part), "=")
+		prefer[strings.ToLower(k)] = strings.Trim(v, `"`)
+	}
+	return prefer
+}
+
+func mockResponse(operation *openapi3.Operation, code string) (int, *openapi3.Response) {
+	if operation.Responses == nil {
+		return http.StatusOK, nil
+	}
+	responses := operation.Responses.Map()
+
+	if code != "" {
+		if ref, ok := responses[code]; ok {
+			status, _ := strconv.Atoi(code)
+			return status, ref.Value
+		}
+		status, err := strconv.Atoi(code)
+		if err == nil && status >= 100 && status <= 599 {
+			if ref := operation.Responses.Default(); ref != nil {
+				return status, ref.Value
+			}
+			return status, nil
+		}
+	}
+
+	codes := make([]string, 0, len(responses))
+	for c := range responses {
+		codes = append(codes, c)
+	}
+	sort.Strings(codes)
+
+	for _, c := range codes {
+		if strings.HasPrefix(c, "2") {
+			status, err := strconv.Atoi(c)
+			if err != nil {
+				// 2XX
+				status = http.StatusOK
+			}
+			return status, responses[c].Value
+		}
+	}
+	if ref := operation.Responses.Default(); ref != nil {
+		return http.StatusOK, ref.Value
+	}
+	return http.StatusOK, nil
+}
+
+func mockMediaType(content openapi3.Content) (*openapi3.MediaType, string) {
+	if mediaType := content.Get(mimeJSON); mediaType != nil {
+		return mediaType, mimeJSON
+	}
+
+	types := make([]string, 0, len(content))
+	for t := range content {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	for _, t := range types {
+		return content[t], t
+	}
+	return nil, ""
+}
+
+func mockMediaTypeExample(mediaType *openapi3.MediaType, name string) interface{} {
+	if name != "" {
+		if ref, ok := mediaType.Examples[name]; ok && ref.Value != nil {
+			return ref.Value.Value
+		}
+	}
+	if mediaType.Example != nil {
+		return mediaType.Example
+	}
+
+	names := make([]string, 0, len(mediaType.Examples))
+	for n := range mediaType.Examples {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	for _, n := range names {
+		if ref := mediaType.Examples[n]; ref.Value != nil {
+			return ref.Value.Value
+		}
+	}
+
+	return mockExample(mediaType.Schema, 0)
+}
+
+// mockExample 由 schema 合成示例值, 优先使用 example / default / enum
+func mockExample(ref *openapi3.SchemaRef, depth int) interface{} {
+	if ref == nil || ref.Value == nil || depth > maxMockSchemaDepth {
+		return nil
+	}
+	schema := ref.Value
+
+	switch {
+	case schema.Example != nil:
+		return schema.Example
+	case schema.Default != nil:
+		return schema.Default
+	case len(schema.Enum) > 0:
+		return schema.Enum[0]
+	case len(schema.AllOf) > 0:
+		merged := map[string]interface{}{}
+		for _, sub := range schema.AllOf {
+			if v, ok := mockExample(sub, depth+1).(map[string]interface{}); ok {
+				for k := range v {
+					merged[k] = v[k]
+				}
+			}
+		}
+		return merged
+	case len(schema.OneOf) > 0:
+		return mockExample(schema.OneOf[0], depth+1)
+	case len(schema.AnyOf) > 0:
+		return mockExample(schema.AnyOf[0], depth+1)
+	}
+
+	switch {
+	case schema.Type.Is(openapi3.TypeObject) || (schema.Type == nil && len(schema.Properties) > 0):
+		obj := map[string]interface{}{}
+		for name, prop := range schema.Properties {
+			obj[name] = mockExample(prop, depth+1)
+		}
+		return obj
+	case schema.Type.Is(openapi3.TypeArray):
+		n := int(schema.MinItems)
+		if n == 0 {
+			n = 1
+		}
+		list := make([]interface{}, n)
+		for i := range list {
+			list[i] = mockExample(schema.Items, depth+1)
+		}
+		return list
+	case schema.Type.Is(openapi3.TypeString):
+		return mockString(schema)
+	case schema.Type.Is(openapi3.TypeInteger):
+		if schema.Min != nil {
+			return int64(*schema.Min)
+		}
+		return 0
+	case schema.Type.Is(openapi3.TypeNumber):
+		if schema.Min != nil {
+			return *schema.Min
+		}
+		return 0.0
+	case schema.Type.Is(openapi3.TypeBoolean):
+		return true
+	}
+	return nil
+}
+
+func mockString(schema *openapi3.Schema) string {
+	var v string
+	switch schema.Format {
+	case "date-time":
+		v = time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC).Format(time.RFC3339)
+	case "date":
+		v = "2006-01-02"
+	case "uuid":
+		v = "00000000-0000-0000-0000-000000000000"
+	case "email":
+		v = "user@example.com"
+	case "uri", "url":
+		v = "https://example.com"
+	case "ipv4":
+		v = "127.0.0.1"
+	case "byte":
+		v = "c3RyaW5n"
+	default:
+		v = "string"
+	}
+	for uint64(len(v)) < schema.MinLength {
+		v += "x"
+	}
+	if schema.MaxLength != nil && uint64(len(v)) > *schema.MaxLength {
+		v = v[:*schema.MaxLength]
+	}
+	return v
+}
@@ -0,0 +1,72 @@
+package confserver
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// mockTestSpec 中的路径为 SvcRootRouter 下的完整路径
+const mockTestSpec = `{
+  "openapi": "3.0.3",
+  "info": {"title": "mock", "version": "1.0.0"},
+  "paths": {
+    "%s": {
+      "parameters": [{"name": "id", "in": "path", "required": true, "schema": {"type": "string"}}],
+      "get": {
+        "responses": {"200": {"description": "ok", "content": {"application/json": {"example": {"name": "mocked"}}}}}
+      },
+      "post": {
+        "responses": {"200": {"description": "ok", "content": {"application/json": {"example": {"name": "mocked"}}}}}
+      }
+    }
+  }
+}`
+
+type mockTestUser struct {
+	ID string `name:"id" in:"path"`
+}
+
+func TestMockServerKeepsRegisteredRoutes(t *testing.T) {
+	specFile := filepath.Join(t.TempDir(), "openapi.json")
+
+	s := &Server{OpenAPISpec: specFile, MockServer: true}
+	s.SetDefaults()
+	s.Init()
+
+	basePath := s.SvcRootRouter().BasePath()
+	spec := fmt.Sprintf(mockTestSpec, joinRoutePath(basePath, "/users/{id}"))
+	if err := os.WriteFile(specFile, []byte(spec), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	RegisterRoute(s, http.MethodPost, "/users/:id", func(ctx context.Context, req *mockTestUser) (map[string]string, error) {
+		return map[string]string{"name": "real-" + req.ID}, nil
+	})
+
+	s.registerMockRoutes()
+	// 重复注册不会 panic
+	s.registerMockRoutes()
+
+	cases := []struct {
+		method string
+		status int
+		body   string
+	}{
+		{method: http.MethodPost, status: http.StatusCreated, body: "real-1"},
+		{method: http.MethodGet, status: http.StatusOK, body: "mocked"},
+	}
+	target := joinRoutePath(basePath, "/users/1")
+	for _, c := range cases {
+		rw := httptest.NewRecorder()
+		s.Engine().ServeHTTP(rw, httptest.NewRequest(c.method, target, nil))
+		if rw.Code != c.status || !strings.Contains(rw.Body.String(), c.body) {
+			t.Fatalf("%s %s = %d %s, want %d %s", c.method, target, rw.Code, rw.Body, c.status, c.body)
+		}
+	}
+}
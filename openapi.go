@@ -0,0 +1,322 @@
+package confserver
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-courier/statuserror"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	openAPIFormatJSON = "json"
+	openAPIFormatYAML = "yaml"
+
+	mimeJSON = "application/json"
+	mimeYAML = "application/yaml"
+)
+
+var errOpenAPISpecNotFound = errors.New("openapi spec not found")
+
+type openAPIDoc struct {
+	json     []byte
+	yaml     []byte
+	etag     string
+	modTime  time.Time
+	size     int64
+	yamlErr  error
+	yamlOnce sync.Once
+
+	router     *openAPIRouter
+	routerErr  error
+	routerOnce sync.Once
+}
+
+func (d *openAPIDoc) YAML() ([]byte, error) {
+	d.yamlOnce.Do(func() {
+		d.yaml, d.yamlErr = JSONToYAML(d.json)
+	})
+	return d.yaml, d.yamlErr
+}
+
+// openAPISpec 缓存 OpenAPISpec 内容, 优先读取 fsys, 不存在时回退到文件路径,
+// 文件只在首次访问时加载 (含不存在的结果), watch 时由 watch 定时检查变更,
+// 均不存在时按 RegisterRoute 注册的路由生成
+type openAPISpec struct {
+	path string
+	fsys fs.FS
+	name string
+
+	mu         sync.RWMutex
+	doc        *openAPIDoc
+	docErr     error
+	fileLoaded bool
+	embed      *openAPIDoc
+	noFsys     bool
+
+	operations []*routeOperation
+	generated  *openAPIDoc
+}
+
+func newOpenAPISpec(path string, fsys fs.FS, name string) *openAPISpec {
+	return &openAPISpec{path: path, fsys: fsys, name: name}
+}
+
+func (s *openAPISpec) Doc() (*openAPIDoc, error) {
+	doc, err := s.specDoc()
+	if errors.Is(err, errOpenAPISpecNotFound) {
+		if generated, genErr := s.generatedDoc(); generated != nil || genErr != nil {
+			return generated, genErr
+		}
+	}
+	return doc, err
+}
+
+func (s *openAPISpec) specDoc() (*openAPIDoc, error) {
+	if doc, err := s.fsDoc(); doc != nil || err != nil {
+		return doc, err
+	}
+
+	s.mu.RLock()
+	doc, err, loaded := s.doc, s.docErr, s.fileLoaded
+	s.mu.RUnlock()
+
+	if loaded {
+		return doc, err
+	}
+
+	s.reloadFile()
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.doc, s.docErr
+}
+
+// reloadFile 文件变更 (含新增及删除) 时重新加载, 返回是否变更
+func (s *openAPISpec) reloadFile() (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	first := !s.fileLoaded
+	s.fileLoaded = true
+
+	info, err := os.Stat(s.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			err = errOpenAPISpecNotFound
+		}
+		changed := first || s.doc != nil
+		s.doc, s.docErr = nil, err
+		return changed, err
+	}
+
+	if s.doc != nil && s.doc.modTime.Equal(info.ModTime()) && s.doc.size == info.Size() {
+		return false, nil
+	}
+
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		// keep serving the previous spec
+		if s.doc == nil {
+			s.docErr = err
+		}
+		return false, err
+	}
+
+	s.doc = newOpenAPIDoc(data)
+	s.doc.modTime = info.ModTime()
+	s.doc.size = info.Size()
+	s.docErr = nil
+	return true, nil
+}
+
+// watch 定时检查 OpenAPISpec 文件变更, 请求路径上不再访问文件系统
+func (s *openAPISpec) watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			changed, err := s.reloadFile()
+			if err != nil && !errors.Is(err, errOpenAPISpecNotFound) {
+				logrus.WithField("tag", "openapi").WithError(err).Error("reload openapi spec failed")
+				continue
+			}
+			if changed {
+				logrus.WithFields(logrus.Fields{
+					"tag":  "openapi",
+					"path": s.path,
+				}).Info("openapi spec reloaded")
+			}
+		}
+	}
+}
+
+// fsDoc fs.FS 中的内容随二进制发布, 只需加载一次
+func (s *openAPISpec) fsDoc() (*openAPIDoc, error) {
+	if s.fsys == nil {
+		return nil, nil
+	}
+
+	s.mu.RLock()
+	doc, noFsys := s.embed, s.noFsys
+	s.mu.RUnlock()
+
+	if doc != nil || noFsys {
+		return doc, nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, err := fs.ReadFile(s.fsys, s.name)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			s.noFsys = true
+			return nil, nil
+		}
+		return nil, err
+	}
+	s.embed = newOpenAPIDoc(data)
+	return s.embed, nil
+}
+
+// generatedDoc 注册新的路由后重新生成
+func (s *openAPISpec) generatedDoc() (*openAPIDoc, error) {
+	s.mu.RLock()
+	doc, n := s.generated, len(s.operations)
+	s.mu.RUnlock()
+
+	if doc != nil || n == 0 {
+		return doc, nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.generated == nil {
+		data, err := generateOpenAPI(s.operations)
+		if err != nil {
+			return nil, err
+		}
+		s.generated = newOpenAPIDoc(data)
+	}
+	return s.generated, nil
+}
+
+func (s *openAPISpec) addOperation(op *routeOperation) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.operations = append(s.operations, op)
+	s.generated = nil
+}
+
+func newOpenAPIDoc(data []byte) *openAPIDoc {
+	sum := sha256.Sum256(data)
+	return &openAPIDoc{
+		json: data,
+		etag: hex.EncodeToString(sum[:8]),
+	}
+}
+
+// SetOpenAPIFS 从 fsys (如 go:embed) 读取 OpenAPI spec, 需在 Init 前调用.
+// name 默认为 OpenAPISpec 去掉 ./ 前缀, fsys 中不存在时回退到 OpenAPISpec 文件
+func (s *Server) SetOpenAPIFS(fsys fs.FS, name ...string) {
+	s.openapiFS = fsys
+	if len(name) > 0 {
+		s.openapiFSName = name[0]
+	}
+}
+
+func (s *Server) openAPIFSName() string {
+	if s.openapiFSName != "" {
+		return s.openapiFSName
+	}
+	return path.Clean(strings.TrimPrefix(filepath.ToSlash(s.OpenAPISpec), "/"))
+}
+
+func (s *Server) OpenapiHandler(ctx *gin.Context) {
+	doc, err := s.openapi.Doc()
+	if err != nil {
+		code := http.StatusInternalServerError
+		if errors.Is(err, errOpenAPISpecNotFound) {
+			code = http.StatusNotFound
+		}
+		statusErr := statuserror.Wrap(err, code, "OpenAPISpecUnavailable")
+		ctx.AbortWithStatusJSON(statusErr.StatusCode(), statusErr)
+		return
+	}
+
+	ctx.Writer.Header().Add("Vary", "Accept")
+
+	format := openAPIFormat(ctx)
+	etag := `"` + doc.etag + "-" + format + `"`
+	ctx.Header("ETag", etag)
+	ctx.Header("Cache-Control", "no-cache")
+
+	if etagMatch(ctx.GetHeader("If-None-Match"), etag) {
+		ctx.Status(http.StatusNotModified)
+		return
+	}
+
+	if format == openAPIFormatYAML {
+		yamlByte, err := doc.YAML()
+		if err != nil {
+			statusErr := statuserror.Wrap(err, http.StatusInternalServerError, "OpenAPISpecUnavailable")
+			ctx.AbortWithStatusJSON(statusErr.StatusCode(), statusErr)
+			return
+		}
+		ctx.Data(http.StatusOK, mimeYAML+"; charset=utf-8", yamlByte)
+		return
+	}
+	ctx.Data(http.StatusOK, mimeJSON+"; charset=utf-8", doc.json)
+}
+
+// openAPIFormat ?format= 优先, 其次 Accept
+func openAPIFormat(ctx *gin.Context) string {
+	switch strings.ToLower(ctx.Query("format")) {
+	case openAPIFormatYAML, "yml":
+		return openAPIFormatYAML
+	case openAPIFormatJSON:
+		return openAPIFormatJSON
+	}
+
+	for _, accept := range strings.Split(ctx.GetHeader("Accept"), ",") {
+		mediaType := strings.TrimSpace(strings.SplitN(accept, ";", 2)[0])
+		switch mediaType {
+		case mimeJSON:
+			return openAPIFormatJSON
+		case mimeYAML, "application/x-yaml", "text/yaml", "text/x-yaml":
+			return openAPIFormatYAML
+		}
+	}
+	return openAPIFormatJSON
+}
+
+func etagMatch(ifNoneMatch string, etag string) bool {
+	if ifNoneMatch == "" {
+		return false
+	}
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
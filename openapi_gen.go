@@ -0,0 +1,419 @@
+package confserver
+
+import (
+	"context"
+	"encoding"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/go-courier/httptransport/transformers"
+	"github.com/go-courier/httptransport/validator"
+	"github.com/go-courier/statuserror"
+	typesx "github.com/go-courier/x/types"
+	"github.com/kunlun-qilian/confx"
+)
+
+var (
+	typeTime          = reflect.TypeOf(time.Time{})
+	typeFileHeader    = reflect.TypeOf(multipart.FileHeader{})
+	typeReadCloser    = reflect.TypeOf((*io.ReadCloser)(nil)).Elem()
+	typeTextMarshaler = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// openAPIGenerator 按 Bind 使用的 in / name / validate / mime / default tag 生成 OpenAPI 3 文档,
+// 请求体及响应中的具名结构体收录到 components/schemas
+type openAPIGenerator struct {
+	doc          *openapi3.T
+	schemaNames  map[reflect.Type]string
+	schemaTypes  map[string]reflect.Type
+	operationIDs map[string]bool
+}
+
+func generateOpenAPI(operations []*routeOperation) ([]byte, error) {
+	version := os.Getenv("PROJECT_VERSION")
+	if version == "" {
+		version = "0.0.0"
+	}
+
+	g := &openAPIGenerator{
+		doc: &openapi3.T{
+			OpenAPI: "3.0.3",
+			Info: &openapi3.Info{
+				Title:   confx.Config.ServiceName(),
+				Version: version,
+			},
+			Paths: openapi3.NewPaths(),
+			Components: &openapi3.Components{
+				Schemas: openapi3.Schemas{},
+			},
+		},
+		schemaNames:  map[reflect.Type]string{},
+		schemaTypes:  map[string]reflect.Type{},
+		operationIDs: map[string]bool{},
+	}
+
+	for _, op := range operations {
+		g.addOperation(op)
+	}
+
+	return j.Marshal(g.doc)
+}
+
+func (g *openAPIGenerator) addOperation(op *routeOperation) {
+	operation := openapi3.NewOperation()
+	operation.OperationID = g.operationID(op)
+	operation.Summary = op.summary
+	operation.Description = op.description
+	operation.Tags = op.tags
+	operation.Deprecated = op.deprecated
+
+	g.addParameters(operation, op.req)
+
+	operation.Responses = openapi3.NewResponses(
+		openapi3.WithName("default", openapi3.NewResponse().
+			WithDescription("error").
+			WithJSONSchemaRef(g.schemaRef(reflect.TypeOf(statuserror.StatusErr{}), "json"))),
+	)
+	for status, response := range g.responses(op.method, op.resp) {
+		operation.Responses.Set(status, &openapi3.ResponseRef{Value: response})
+	}
+
+	g.doc.AddOperation(openAPIPathOf(op.path), op.method, operation)
+}
+
+var nonIdentifier = regexp.MustCompile(`[^A-Za-z0-9]+`)
+
+// operationID 默认为请求结构体类型名, 重复时按 method 和 path 生成
+func (g *openAPIGenerator) operationID(op *routeOperation) string {
+	id := op.operationID
+	if id == "" && !g.operationIDs[op.req.Name()] {
+		id = schemaNameOf(op.req)
+	}
+	if id == "" || g.operationIDs[id] {
+		id = strings.Trim(nonIdentifier.ReplaceAllString(strings.ToLower(op.method)+"_"+op.path, "_"), "_")
+	}
+	g.operationIDs[id] = true
+	return id
+}
+
+func (g *openAPIGenerator) addParameters(operation *openapi3.Operation, req reflect.Type) {
+	req = derefType(req)
+	if req.Kind() != reflect.Struct {
+		return
+	}
+
+	transformers.EachParameter(context.Background(), typesx.FromRType(req), func(p *transformers.Parameter) bool {
+		omitempty := false
+		if tag, ok := p.Tags["name"]; ok {
+			omitempty = tag.HasFlag("omitempty")
+		}
+		_, hasDefault := p.Tags["default"]
+		fieldType := rtypeOf(p.Type)
+
+		switch p.In {
+		case "body":
+			mediaType := mediaTypeOfMIME(p.Tags["mime"].Name())
+			tagKey := "json"
+			if mediaType == "multipart/form-data" || mediaType == "application/x-www-form-urlencoded" {
+				tagKey = "name"
+			}
+			body := openapi3.NewRequestBody().
+				WithRequired(!omitempty).
+				WithContent(openapi3.NewContentWithSchemaRef(g.schemaRef(fieldType, tagKey), []string{mediaType}))
+			operation.RequestBody = &openapi3.RequestBodyRef{Value: body}
+		case "path", "query", "header", "cookie":
+			schema := g.schemaRef(fieldType, "json")
+			applyValidateRule(schema, string(p.Tags["validate"]), string(p.Tags["default"]), fieldType)
+			param := &openapi3.Parameter{
+				Name:     p.Name,
+				In:       p.In,
+				Required: p.In == "path" || (!omitempty && !hasDefault),
+				Schema:   schema,
+			}
+			operation.AddParameter(param)
+		}
+		return true
+	})
+}
+
+// responses 与 httpx.Response.WriteTo 的默认状态码一致, POST 为 201, 其余为 200,
+// 空结构体输出 {}, 仅 Resp 为 interface 时可能返回 nil, 此时为 204
+func (g *openAPIGenerator) responses(method string, resp reflect.Type) map[string]*openapi3.Response {
+	status, description := "200", "OK"
+	if method == http.MethodPost {
+		status, description = "201", "Created"
+	}
+
+	responses := map[string]*openapi3.Response{
+		status: openapi3.NewResponse().WithDescription(description).WithJSONSchemaRef(g.schemaRef(resp, "json")),
+	}
+	if resp.Kind() == reflect.Interface {
+		responses["204"] = openapi3.NewResponse().WithDescription("No Content")
+	}
+	return responses
+}
+
+// schemaRef json 下具名结构体引用 components/schemas, 表单及匿名结构体内联展开
+func (g *openAPIGenerator) schemaRef(t reflect.Type, tagKey string) *openapi3.SchemaRef {
+	t = derefType(t)
+
+	if t.Kind() == reflect.Struct && tagKey == "json" && t.Name() != "" && !isScalarStruct(t) {
+		name, ok := g.schemaNames[t]
+		if !ok {
+			name = g.uniqueSchemaName(t)
+			g.schemaNames[t] = name
+			g.schemaTypes[name] = t
+			// 先登记名称, 自引用的结构体直接使用 $ref
+			g.doc.Components.Schemas[name] = openapi3.NewSchemaRef("", g.structSchema(t, tagKey))
+		}
+		return openapi3.NewSchemaRef("#/components/schemas/"+name, nil)
+	}
+
+	return openapi3.NewSchemaRef("", g.schema(t, tagKey))
+}
+
+func (g *openAPIGenerator) schema(t reflect.Type, tagKey string) *openapi3.Schema {
+	switch {
+	case t == typeTime:
+		return openapi3.NewDateTimeSchema()
+	case t == typeFileHeader || t == typeReadCloser:
+		return openapi3.NewStringSchema().WithFormat("binary")
+	case t.Implements(typeTextMarshaler) || reflect.PointerTo(t).Implements(typeTextMarshaler):
+		return openapi3.NewStringSchema()
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return openapi3.NewBoolSchema()
+	case reflect.Int, reflect.Int64:
+		return openapi3.NewInt64Schema()
+	case reflect.Int8, reflect.Int16, reflect.Int32:
+		return openapi3.NewInt32Schema()
+	case reflect.Uint, reflect.Uint64, reflect.Uintptr:
+		return openapi3.NewInt64Schema().WithMin(0)
+	case reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return openapi3.NewInt32Schema().WithMin(0)
+	case reflect.Float32:
+		return openapi3.NewFloat64Schema().WithFormat("float")
+	case reflect.Float64:
+		return openapi3.NewFloat64Schema().WithFormat("double")
+	case reflect.String:
+		return openapi3.NewStringSchema()
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return openapi3.NewBytesSchema()
+		}
+		schema := openapi3.NewArraySchema()
+		schema.Items = g.schemaRef(t.Elem(), tagKey)
+		return schema
+	case reflect.Map:
+		schema := openapi3.NewObjectSchema()
+		schema.AdditionalProperties = openapi3.AdditionalProperties{Schema: g.schemaRef(t.Elem(), tagKey)}
+		return schema
+	case reflect.Struct:
+		return g.structSchema(t, tagKey)
+	}
+	return openapi3.NewSchema()
+}
+
+func (g *openAPIGenerator) structSchema(t reflect.Type, tagKey string) *openapi3.Schema {
+	schema := openapi3.NewObjectSchema()
+
+	typesx.EachField(typesx.FromRType(t), tagKey, func(field typesx.StructField, name string, omitempty bool) bool {
+		fieldType := rtypeOf(field.Type())
+		tag := field.Tag()
+
+		prop := g.schemaRef(fieldType, tagKey)
+		applyValidateRule(prop, tag.Get("validate"), tag.Get("default"), fieldType)
+		schema.Properties[name] = prop
+
+		if _, hasDefault := tag.Lookup("default"); !omitempty && !hasDefault {
+			schema.Required = append(schema.Required, name)
+		}
+		return true
+	})
+
+	return schema
+}
+
+func (g *openAPIGenerator) uniqueSchemaName(t reflect.Type) string {
+	name := schemaNameOf(t)
+	if _, ok := g.schemaTypes[name]; !ok {
+		return name
+	}
+	pkg := t.PkgPath()
+	if i := strings.LastIndex(pkg, "/"); i >= 0 {
+		pkg = pkg[i+1:]
+	}
+	name = strings.ToUpper(pkg[:1]) + pkg[1:] + name
+	for i := 2; ; i++ {
+		if _, ok := g.schemaTypes[name]; !ok {
+			return name
+		}
+		name = fmt.Sprintf("%s%d", strings.TrimRight(name, "0123456789"), i)
+	}
+}
+
+// schemaNameOf 泛型实例化的类型名去掉类型参数中的非法字符
+func schemaNameOf(t reflect.Type) string {
+	return nonIdentifier.ReplaceAllString(t.Name(), "")
+}
+
+// applyValidateRule 将 validate 规则转换为 schema 约束, 引用类型不展开,
+// 无法编译的规则 (如未注册的 strfmt) 忽略, 由 Bind 在请求时报错
+func applyValidateRule(ref *openapi3.SchemaRef, rule string, defaultValue string, t reflect.Type) {
+	schema := ref.Value
+	if schema == nil {
+		return
+	}
+	if defaultValue != "" {
+		schema.Default = defaultValueOf(defaultValue, derefType(t))
+	}
+	if rule == "" || rule == "-" {
+		return
+	}
+
+	if v, err := validator.ValidatorMgrDefault.Compile(context.Background(), []byte(rule), typesx.FromRType(t)); err == nil {
+		applyValidator(schema, v)
+	}
+}
+
+func applyValidator(schema *openapi3.Schema, v validator.Validator) {
+	if loader, ok := v.(*validator.ValidatorLoader); ok {
+		v = loader.Validator
+	}
+
+	switch x := v.(type) {
+	case *validator.StringValidator:
+		schema.MinLength = x.MinLength
+		schema.MaxLength = x.MaxLength
+		schema.Pattern = x.Pattern
+		for _, e := range x.Enums {
+			schema.Enum = append(schema.Enum, e)
+		}
+	case *validator.StrfmtValidator:
+		schema.Format = strings.TrimPrefix(x.String(), "@")
+	case *validator.IntValidator:
+		if x.Minimum != nil && *x.Minimum != validator.MinInt(x.BitSize) {
+			schema.WithMin(float64(*x.Minimum))
+		}
+		if x.Maximum != nil && *x.Maximum != validator.MaxInt(x.BitSize) {
+			schema.WithMax(float64(*x.Maximum))
+		}
+		schema.ExclusiveMin, schema.ExclusiveMax = x.ExclusiveMinimum, x.ExclusiveMaximum
+		if x.MultipleOf != 0 {
+			multipleOf := float64(x.MultipleOf)
+			schema.MultipleOf = &multipleOf
+		}
+		for _, e := range x.Enums {
+			schema.Enum = append(schema.Enum, e)
+		}
+	case *validator.UintValidator:
+		schema.WithMin(float64(x.Minimum))
+		if x.Maximum != validator.MaxUint(x.BitSize) {
+			schema.WithMax(float64(x.Maximum))
+		}
+		schema.ExclusiveMin, schema.ExclusiveMax = x.ExclusiveMinimum, x.ExclusiveMaximum
+		if x.MultipleOf != 0 {
+			multipleOf := float64(x.MultipleOf)
+			schema.MultipleOf = &multipleOf
+		}
+		for _, e := range x.Enums {
+			schema.Enum = append(schema.Enum, e)
+		}
+	case *validator.FloatValidator:
+		schema.Min, schema.Max = x.Minimum, x.Maximum
+		schema.ExclusiveMin, schema.ExclusiveMax = x.ExclusiveMinimum, x.ExclusiveMaximum
+		if x.MultipleOf != 0 {
+			multipleOf := x.MultipleOf
+			schema.MultipleOf = &multipleOf
+		}
+		for _, e := range x.Enums {
+			schema.Enum = append(schema.Enum, e)
+		}
+	case *validator.SliceValidator:
+		schema.MinItems = x.MinItems
+		schema.MaxItems = x.MaxItems
+		if x.ElemValidator != nil && schema.Items != nil && schema.Items.Value != nil {
+			applyValidator(schema.Items.Value, x.ElemValidator)
+		}
+	case *validator.MapValidator:
+		schema.MinProps = x.MinProperties
+		schema.MaxProps = x.MaxProperties
+	}
+}
+
+// defaultValueOf default tag 按字段类型转换, 与 schema type 保持一致
+func defaultValueOf(value string, t reflect.Type) interface{} {
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if v, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return v
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if v, err := strconv.ParseUint(value, 10, 64); err == nil {
+			return v
+		}
+	case reflect.Float32, reflect.Float64:
+		if v, err := strconv.ParseFloat(value, 64); err == nil {
+			return v
+		}
+	case reflect.Bool:
+		if v, err := strconv.ParseBool(value); err == nil {
+			return v
+		}
+	}
+	return value
+}
+
+// mediaTypeOfMIME 将 mime tag 的 transformer 名称转换为 media type
+func mediaTypeOfMIME(mime string) string {
+	switch mime {
+	case "", "json":
+		return mimeJSON
+	case "xml":
+		return "application/xml"
+	case "plain", "text":
+		return "text/plain"
+	case "html":
+		return "text/html"
+	case "stream", "octet-stream":
+		return "application/octet-stream"
+	case "multipart", "form-data":
+		return "multipart/form-data"
+	case "urlencoded", "form", "x-www-form-urlencoded":
+		return "application/x-www-form-urlencoded"
+	}
+	if strings.Contains(mime, "/") {
+		return mime
+	}
+	return "application/" + mime
+}
+
+// isScalarStruct time.Time 等以字符串表示的结构体
+func isScalarStruct(t reflect.Type) bool {
+	return t == typeTime || t == typeFileHeader || reflect.PointerTo(t).Implements(typeTextMarshaler)
+}
+
+func derefType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t
+}
+
+func rtypeOf(t typesx.Type) reflect.Type {
+	if rt, ok := t.(*typesx.RType); ok {
+		return rt.Type
+	}
+	return reflect.TypeOf((*interface{})(nil)).Elem()
+}
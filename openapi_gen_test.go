@@ -0,0 +1,314 @@
+package confserver
+
+import (
+	"context"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/gin-gonic/gin"
+	logtest "github.com/sirupsen/logrus/hooks/test"
+)
+
+type genListUsers struct {
+	OrgID   string `name:"orgID" in:"path"`
+	Size    int    `name:"size,omitempty" in:"query" validate:"@int[1,100]" default:"10"`
+	Keyword string `name:"keyword" in:"query" validate:"@string[1,32]"`
+	State   string `name:"state,omitempty" in:"query" validate:"@string{ACTIVE,DISABLED}"`
+	TraceID string `name:"X-Trace-Id,omitempty" in:"header"`
+}
+
+type genUser struct {
+	Name      string            `json:"name" validate:"@string[1,]"`
+	Age       uint8             `json:"age,omitempty"`
+	Tags      []string          `json:"tags,omitempty" validate:"@slice<@string[1,]>[,5]"`
+	Labels    map[string]string `json:"labels,omitempty"`
+	CreatedAt time.Time         `json:"createdAt"`
+	Manager   *genUser          `json:"manager,omitempty"`
+}
+
+type genCreateUser struct {
+	OrgID string  `name:"orgID" in:"path"`
+	Data  genUser `in:"body"`
+}
+
+type genUploadAvatar struct {
+	Data struct {
+		Name string                `name:"name"`
+		File *multipart.FileHeader `name:"file"`
+	} `in:"body" mime:"multipart"`
+}
+
+type genPage[T any] struct {
+	Total int `json:"total"`
+	Items []T `json:"items"`
+}
+
+func genOperation[Req any, Resp any](method string, path string, opts ...RouteOption) *routeOperation {
+	op := &routeOperation{
+		method: method,
+		path:   path,
+		req:    reflect.TypeOf((*Req)(nil)).Elem(),
+		resp:   reflect.TypeOf((*Resp)(nil)).Elem(),
+	}
+	for _, opt := range opts {
+		opt(op)
+	}
+	return op
+}
+
+func loadGeneratedOpenAPI(t *testing.T, operations ...*routeOperation) *openapi3.T {
+	t.Helper()
+
+	data, err := generateOpenAPI(operations)
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, err := openapi3.NewLoader().LoadFromData(data)
+	if err != nil {
+		t.Fatalf("load: %v\n%s", err, data)
+	}
+	if err := doc.Validate(context.Background()); err != nil {
+		t.Fatalf("validate: %v\n%s", err, data)
+	}
+	return doc
+}
+
+func TestGenerateOpenAPI(t *testing.T) {
+	cases := []struct {
+		name       string
+		operations []*routeOperation
+		check      func(t *testing.T, doc *openapi3.T)
+	}{
+		{
+			name:       "parameters",
+			operations: []*routeOperation{genOperation[genListUsers, genPage[genUser]](http.MethodGet, "/orgs/:orgID/users")},
+			check: func(t *testing.T, doc *openapi3.T) {
+				op := doc.Paths.Find("/orgs/{orgID}/users").Get
+				if op.OperationID != "genListUsers" {
+					t.Fatalf("operationId = %s", op.OperationID)
+				}
+
+				orgID := op.Parameters.GetByInAndName("path", "orgID")
+				if orgID == nil || !orgID.Required {
+					t.Fatalf("path param orgID should be required: %+v", orgID)
+				}
+
+				size := op.Parameters.GetByInAndName("query", "size").Schema.Value
+				if !size.Type.Is("integer") || *size.Min != 1 || *size.Max != 100 || size.Default != float64(10) {
+					t.Fatalf("unexpected size schema: %+v", size)
+				}
+				if op.Parameters.GetByInAndName("query", "size").Required {
+					t.Fatal("query param with default should be optional")
+				}
+
+				keyword := op.Parameters.GetByInAndName("query", "keyword")
+				if !keyword.Required || keyword.Schema.Value.MinLength != 1 || *keyword.Schema.Value.MaxLength != 32 {
+					t.Fatalf("unexpected keyword param: %+v", keyword.Schema.Value)
+				}
+
+				if state := op.Parameters.GetByInAndName("query", "state").Schema.Value; len(state.Enum) != 2 {
+					t.Fatalf("unexpected state enum: %v", state.Enum)
+				}
+
+				if op.Parameters.GetByInAndName("header", "X-Trace-Id") == nil {
+					t.Fatal("header param missing")
+				}
+			},
+		},
+		{
+			name:       "json body",
+			operations: []*routeOperation{genOperation[genCreateUser, genUser](http.MethodPost, "/orgs/:orgID/users", WithTags("user"), WithSummary("create user"))},
+			check: func(t *testing.T, doc *openapi3.T) {
+				op := doc.Paths.Find("/orgs/{orgID}/users").Post
+				if op.Summary != "create user" || len(op.Tags) != 1 || op.Tags[0] != "user" {
+					t.Fatalf("unexpected operation: %+v", op)
+				}
+
+				body := op.RequestBody.Value
+				if !body.Required || body.Content.Get(mimeJSON).Schema.Ref != "#/components/schemas/genUser" {
+					t.Fatalf("unexpected request body: %+v", body.Content.Get(mimeJSON).Schema)
+				}
+
+				user := doc.Components.Schemas["genUser"].Value
+				if got, want := user.Required, []string{"name", "createdAt"}; !reflect.DeepEqual(got, want) {
+					t.Fatalf("required = %v, want %v", got, want)
+				}
+				if user.Properties["createdAt"].Value.Format != "date-time" {
+					t.Fatal("time.Time should be date-time")
+				}
+				if user.Properties["manager"].Ref != "#/components/schemas/genUser" {
+					t.Fatalf("self reference = %q", user.Properties["manager"].Ref)
+				}
+				if tags := user.Properties["tags"].Value; *tags.MaxItems != 5 || tags.Items.Value.MinLength != 1 {
+					t.Fatalf("unexpected tags schema: %+v", tags)
+				}
+				if labels := user.Properties["labels"].Value; labels.AdditionalProperties.Schema == nil {
+					t.Fatal("map should use additionalProperties")
+				}
+
+				if ref := op.Responses.Status(http.StatusCreated).Value.Content.Get(mimeJSON).Schema.Ref; ref != "#/components/schemas/genUser" {
+					t.Fatalf("response ref = %q", ref)
+				}
+				if op.Responses.Default() == nil {
+					t.Fatal("default error response missing")
+				}
+			},
+		},
+		{
+			name:       "multipart body",
+			operations: []*routeOperation{genOperation[genUploadAvatar, struct{}](http.MethodPut, "/avatar")},
+			check: func(t *testing.T, doc *openapi3.T) {
+				op := doc.Paths.Find("/avatar").Put
+
+				schema := op.RequestBody.Value.Content.Get("multipart/form-data").Schema.Value
+				if schema == nil || schema.Properties["file"].Value.Format != "binary" {
+					t.Fatalf("unexpected multipart schema: %+v", schema)
+				}
+				// 空结构体输出 {}
+				resp := op.Responses.Status(http.StatusOK)
+				if resp == nil || !resp.Value.Content.Get(mimeJSON).Schema.Value.Type.Is("object") {
+					t.Fatal("empty struct response should be 200 {}")
+				}
+				if op.Responses.Status(http.StatusNoContent) != nil {
+					t.Fatal("empty struct response is never 204")
+				}
+			},
+		},
+		{
+			name:       "nullable response",
+			operations: []*routeOperation{genOperation[genListUsers, any](http.MethodDelete, "/orgs/:orgID/users")},
+			check: func(t *testing.T, doc *openapi3.T) {
+				op := doc.Paths.Find("/orgs/{orgID}/users").Delete
+				if op.Responses.Status(http.StatusOK) == nil || op.Responses.Status(http.StatusNoContent) == nil {
+					t.Fatal("interface response should be 200 or 204")
+				}
+			},
+		},
+		{
+			name: "operation id",
+			operations: []*routeOperation{
+				genOperation[genListUsers, genPage[genUser]](http.MethodGet, "/orgs/:orgID/users"),
+				genOperation[genListUsers, genPage[genUser]](http.MethodGet, "/orgs/:orgID/members"),
+				genOperation[genCreateUser, genUser](http.MethodPost, "/orgs/:orgID/users", WithOperationID("createUser")),
+			},
+			check: func(t *testing.T, doc *openapi3.T) {
+				for path, id := range map[string]string{
+					"/orgs/{orgID}/users":   "genListUsers",
+					"/orgs/{orgID}/members": "get_orgs_orgID_members",
+				} {
+					if got := doc.Paths.Find(path).Get.OperationID; got != id {
+						t.Fatalf("%s operationId = %s, want %s", path, got, id)
+					}
+				}
+				if got := doc.Paths.Find("/orgs/{orgID}/users").Post.OperationID; got != "createUser" {
+					t.Fatalf("operationId = %s, want createUser", got)
+				}
+			},
+		},
+		{
+			name:       "generic schema name",
+			operations: []*routeOperation{genOperation[genListUsers, genPage[genUser]](http.MethodGet, "/orgs/:orgID/users")},
+			check: func(t *testing.T, doc *openapi3.T) {
+				ref := doc.Paths.Find("/orgs/{orgID}/users").Get.Responses.Status(http.StatusOK).Value.Content.Get(mimeJSON).Schema.Ref
+				name := ref[len("#/components/schemas/"):]
+				if nonIdentifier.MatchString(name) {
+					t.Fatalf("invalid schema name %q", name)
+				}
+				if doc.Components.Schemas[name] == nil {
+					t.Fatalf("schema %q missing", name)
+				}
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			c.check(t, loadGeneratedOpenAPI(t, c.operations...))
+		})
+	}
+}
+
+func TestRegisterRouteOpenAPI(t *testing.T) {
+	s := &Server{}
+	s.SetDefaults()
+	s.Init()
+
+	RegisterRoute(s, http.MethodPost, "/orgs/:orgID/users", func(ctx context.Context, req *genCreateUser) (*genUser, error) {
+		return &req.Data, nil
+	})
+
+	doc, err := s.openapi.generatedDoc()
+	if err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := openapi3.NewLoader().LoadFromData(doc.json)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := loaded.Validate(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	path := joinRoutePath(s.SvcRootRouter().BasePath(), "/orgs/{orgID}/users")
+	if loaded.Paths.Find(path) == nil || loaded.Paths.Find(path).Post == nil {
+		t.Fatalf("operation %s missing", path)
+	}
+}
+
+// 生成的文档与 Handle 实际输出的状态码及响应体一致, 响应校验不产生 contract violation
+func TestRegisterRouteResponseContract(t *testing.T) {
+	t.Cleanup(func() {
+		gin.SetMode(gin.TestMode)
+	})
+
+	s := &Server{Mode: "debug"}
+	s.SetDefaults()
+	s.Init()
+
+	RegisterRoute(s, http.MethodPost, "/orgs/:orgID/users", func(ctx context.Context, req *genCreateUser) (*genUser, error) {
+		return &req.Data, nil
+	})
+	RegisterRoute(s, http.MethodGet, "/orgs/:orgID/users", func(ctx context.Context, req *genListUsers) (struct{}, error) {
+		return struct{}{}, nil
+	})
+	RegisterRoute(s, http.MethodDelete, "/orgs/:orgID/users", func(ctx context.Context, req *genListUsers) (any, error) {
+		return nil, nil
+	})
+
+	hook := logtest.NewGlobal()
+	defer hook.Reset()
+
+	base := joinRoutePath(s.SvcRootRouter().BasePath(), "/orgs/o1/users")
+	cases := []struct {
+		method string
+		target string
+		body   string
+		status int
+	}{
+		{method: http.MethodPost, target: base, body: `{"name":"a","createdAt":"2024-01-01T00:00:00Z"}`, status: http.StatusCreated},
+		{method: http.MethodGet, target: base + "?keyword=a", status: http.StatusOK},
+		{method: http.MethodDelete, target: base + "?keyword=a", status: http.StatusNoContent},
+	}
+	for _, c := range cases {
+		req := httptest.NewRequest(c.method, c.target, strings.NewReader(c.body))
+		req.Header.Set("Content-Type", mimeJSON)
+		rw := httptest.NewRecorder()
+		s.Engine().ServeHTTP(rw, req)
+
+		if rw.Code != c.status {
+			t.Fatalf("%s %s = %d %s, want %d", c.method, c.target, rw.Code, rw.Body, c.status)
+		}
+	}
+
+	for _, entry := range hook.AllEntries() {
+		if entry.Data["tag"] == "openapi.response" {
+			t.Fatalf("unexpected contract violation: %s %v", entry.Message, entry.Data)
+		}
+	}
+}
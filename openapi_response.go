@@ -0,0 +1,83 @@
+package confserver
+
+import (
+	"bytes"
+
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/gin-gonic/gin"
+)
+
+// 超出后不再校验响应, 避免大文件/流式响应占用内存
+const maxContractCheckBodySize = 1 << 20
+
+// OpenAPIResponseValidator 缓存 handler 写出的响应并按 OpenAPISpec 中对应状态码的 schema 校验,
+// 不一致时记录日志及 span event, 不影响响应本身
+func (s *Server) OpenAPIResponseValidator() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		route := s.routeOf(c)
+		if route == nil {
+			c.Next()
+			return
+		}
+
+		w := &bodyCaptureWriter{ResponseWriter: c.Writer, limit: maxContractCheckBodySize}
+		c.Writer = w
+		defer func() {
+			c.Writer = w.ResponseWriter
+		}()
+
+		c.Next()
+
+		if w.overflow || c.IsAborted() && !w.Written() {
+			return
+		}
+
+		input := &openapi3filter.ResponseValidationInput{
+			RequestValidationInput: &openapi3filter.RequestValidationInput{
+				Request:    c.Request,
+				PathParams: pathParamsOf(c),
+				Route:      route,
+			},
+			Status: w.Status(),
+			Header: w.Header(),
+			Options: &openapi3filter.Options{
+				MultiError:            true,
+				IncludeResponseStatus: true,
+			},
+		}
+		input.SetBodyBytes(w.body.Bytes())
+
+		if err := openapi3filter.ValidateResponse(c.Request.Context(), input); err != nil {
+			reportOpenAPIViolation(c, "openapi.response", openAPIErrorFields(err))
+		}
+	}
+}
+
+type bodyCaptureWriter struct {
+	gin.ResponseWriter
+	body     bytes.Buffer
+	limit    int
+	overflow bool
+}
+
+func (w *bodyCaptureWriter) Write(data []byte) (int, error) {
+	w.capture(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *bodyCaptureWriter) WriteString(s string) (int, error) {
+	w.capture([]byte(s))
+	return w.ResponseWriter.WriteString(s)
+}
+
+func (w *bodyCaptureWriter) capture(data []byte) {
+	if w.overflow {
+		return
+	}
+	if w.body.Len()+len(data) > w.limit {
+		w.overflow = true
+		w.body.Reset()
+		return
+	}
+	w.body.Write(data)
+}
@@ -0,0 +1,121 @@
+package confserver
+
+import (
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// openAPIRouter 按 method 及 gin 路由模板索引 spec 中的 operation
+type openAPIRouter struct {
+	spec   *openapi3.T
+	routes map[string]*routers.Route
+}
+
+func (d *openAPIDoc) Router() (*openAPIRouter, error) {
+	d.routerOnce.Do(func() {
+		d.router, d.routerErr = newOpenAPIRouter(d.json)
+		if d.routerErr != nil {
+			logrus.WithField("tag", "openapi").WithError(d.routerErr).Error("parse openapi spec failed")
+		}
+	})
+	return d.router, d.routerErr
+}
+
+func newOpenAPIRouter(data []byte) (*openAPIRouter, error) {
+	spec, err := openapi3.NewLoader().LoadFromData(data)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &openAPIRouter{
+		spec:   spec,
+		routes: map[string]*routers.Route{},
+	}
+
+	basePaths := []string{""}
+	for _, server := range spec.Servers {
+		if u, err := url.Parse(server.URL); err == nil && strings.Trim(u.Path, "/") != "" {
+			basePaths = append(basePaths, strings.TrimRight(u.Path, "/"))
+		}
+	}
+
+	for p, pathItem := range spec.Paths.Map() {
+		for method, operation := range pathItem.Operations() {
+			route := &routers.Route{
+				Spec:      spec,
+				Path:      p,
+				PathItem:  pathItem,
+				Method:    method,
+				Operation: operation,
+			}
+			for _, basePath := range basePaths {
+				r.routes[routeKey(method, ginPathOf(basePath+p))] = route
+			}
+		}
+	}
+	return r, nil
+}
+
+var openAPIPathParam = regexp.MustCompile(`\{([^}]+)\}`)
+
+// ginPathOf /users/{id} => /users/:id
+func ginPathOf(openAPIPath string) string {
+	return openAPIPathParam.ReplaceAllString(openAPIPath, ":$1")
+}
+
+var ginPathParam = regexp.MustCompile(`/[:*]([^/]+)`)
+
+// openAPIPathOf /users/:id => /users/{id}
+func openAPIPathOf(ginPath string) string {
+	return ginPathParam.ReplaceAllString(ginPath, "/{$1}")
+}
+
+func routeKey(method string, ginPath string) string {
+	return strings.ToUpper(method) + " " + ginPath
+}
+
+// Find 通过 gin 的 FullPath 匹配 operation
+func (r *openAPIRouter) Find(method string, fullPath string) *routers.Route {
+	if fullPath == "" {
+		return nil
+	}
+	if method == http.MethodHead {
+		if route, ok := r.routes[routeKey(method, fullPath)]; ok {
+			return route
+		}
+		method = http.MethodGet
+	}
+	return r.routes[routeKey(method, fullPath)]
+}
+
+func pathParamsOf(c *gin.Context) map[string]string {
+	params := make(map[string]string, len(c.Params))
+	for _, p := range c.Params {
+		// catch-all params keep the leading slash in gin
+		params[p.Key] = strings.TrimPrefix(p.Value, "/")
+	}
+	return params
+}
+
+// routeOf 当前请求对应的 operation, spec 不存在或未声明时返回 nil
+func (s *Server) routeOf(c *gin.Context) *routers.Route {
+	if s.openapi == nil {
+		return nil
+	}
+	doc, err := s.openapi.Doc()
+	if err != nil {
+		return nil
+	}
+	router, err := doc.Router()
+	if err != nil {
+		return nil
+	}
+	return router.Find(c.Request.Method, c.FullPath())
+}
@@ -0,0 +1,61 @@
+package confserver
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestOpenAPISpecFileCache(t *testing.T) {
+	specFile := filepath.Join(t.TempDir(), "openapi.json")
+	spec := newOpenAPISpec(specFile, nil, "")
+
+	if _, err := spec.Doc(); !errors.Is(err, errOpenAPISpecNotFound) {
+		t.Fatalf("err = %v, want not found", err)
+	}
+
+	// 不存在的结果被缓存, 后续请求不再访问文件系统
+	if err := os.WriteFile(specFile, []byte(`{"openapi":"3.0.3"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := spec.Doc(); !errors.Is(err, errOpenAPISpecNotFound) {
+		t.Fatalf("err = %v, want cached not found", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go spec.watch(ctx, 10*time.Millisecond)
+
+	waitFor := func(check func(doc *openAPIDoc, err error) bool) {
+		t.Helper()
+		deadline := time.Now().Add(2 * time.Second)
+		for time.Now().Before(deadline) {
+			if check(spec.Doc()) {
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		t.Fatal("spec not reloaded")
+	}
+
+	waitFor(func(doc *openAPIDoc, err error) bool {
+		return err == nil && string(doc.json) == `{"openapi":"3.0.3"}`
+	})
+
+	if err := os.WriteFile(specFile, []byte(`{"openapi":"3.1.0"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(func(doc *openAPIDoc, err error) bool {
+		return err == nil && string(doc.json) == `{"openapi":"3.1.0"}`
+	})
+
+	if err := os.Remove(specFile); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(func(doc *openAPIDoc, err error) bool {
+		return errors.Is(err, errOpenAPISpecNotFound)
+	})
+}
@@ -0,0 +1,150 @@
+package confserver
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/gin-gonic/gin"
+	"github.com/go-courier/statuserror"
+	trace2 "github.com/kunlun-qilian/confserver/pkg/trace"
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// ValidationEnforce 校验失败返回 400
+	ValidationEnforce = "enforce"
+	// ValidationReport 校验失败仅记录日志及 span
+	ValidationReport = "report"
+)
+
+// OpenAPIRequestValidator 按 OpenAPISpec 校验请求的 path / query / header 参数及 JSON body,
+// spec 中未声明的路由直接放行, enforce 模式下校验失败与 Bind 一样返回 400 BadRequest
+func (s *Server) OpenAPIRequestValidator(mode string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		route := s.routeOf(c)
+		if route == nil {
+			c.Next()
+			return
+		}
+
+		err := openapi3filter.ValidateRequest(c.Request.Context(), &openapi3filter.RequestValidationInput{
+			Request:    c.Request,
+			PathParams: pathParamsOf(c),
+			Route:      route,
+			Options: &openapi3filter.Options{
+				MultiError:          true,
+				SkipSettingDefaults: true,
+				AuthenticationFunc:  openapi3filter.NoopAuthenticationFunc,
+			},
+		})
+		if err == nil {
+			c.Next()
+			return
+		}
+
+		fields := openAPIErrorFields(err)
+		reportOpenAPIViolation(c, "openapi.request", fields)
+
+		if mode != ValidationEnforce {
+			c.Next()
+			return
+		}
+
+		// 与 Bind 的校验错误使用相同的格式输出
+		WriteError(c, badRequest(err, fields))
+	}
+}
+
+func reportOpenAPIViolation(c *gin.Context, name string, fields statuserror.ErrorFields) {
+	ctx := c.Request.Context()
+	traceID, spanID := trace2.TraceAndSpanIDFromContext(ctx)
+
+	logrus.WithFields(logrus.Fields{
+		"tag":         name,
+		"method":      c.Request.Method,
+		"request_url": c.Request.URL.String(),
+		"route":       c.FullPath(),
+		"trace_id":    traceID,
+		"span_id":     spanID,
+	}).Warnf("openapi contract violation %s", fields)
+
+	if span := trace2.GetTraceSpanFromContext(ctx); span != nil {
+		span.TraceSpan().SetAttributes(attribute.Bool(name+".valid", false))
+
+		attrs := make([]attribute.KeyValue, 0, len(fields))
+		for _, f := range fields {
+			attrs = append(attrs, attribute.String(f.In+"."+f.Field, f.Msg))
+		}
+		span.TraceSpan().AddEvent("@"+name+".violation", trace.WithAttributes(attrs...))
+	}
+}
+
+// openAPIErrorFields 将 kin-openapi 的校验错误展开为 statuserror.ErrorFields
+func openAPIErrorFields(err error) statuserror.ErrorFields {
+	var fields statuserror.ErrorFields
+
+	var walk func(in string, field string, err error)
+	walk = func(in string, field string, err error) {
+		switch e := err.(type) {
+		case openapi3.MultiError:
+			for _, item := range e {
+				walk(in, field, item)
+			}
+		case *openapi3filter.RequestError:
+			switch {
+			case e.Parameter != nil:
+				in, field = e.Parameter.In, e.Parameter.Name
+			case e.RequestBody != nil:
+				in = "body"
+			}
+			if e.Err != nil {
+				walk(in, field, e.Err)
+				return
+			}
+			fields = append(fields, statuserror.NewErrorField(in, field, e.Reason))
+		case *openapi3filter.ResponseError:
+			if e.Err != nil {
+				walk("body", field, e.Err)
+				return
+			}
+			fields = append(fields, statuserror.NewErrorField("body", field, e.Reason))
+		case *openapi3.SchemaError:
+			if pointer := e.JSONPointer(); len(pointer) > 0 {
+				field = joinFieldPath(field, pointer)
+			}
+			fields = append(fields, statuserror.NewErrorField(in, field, e.Reason))
+		case *openapi3filter.ParseError:
+			fields = append(fields, statuserror.NewErrorField(in, field, e.Reason))
+		default:
+			if inner := errors.Unwrap(err); inner != nil {
+				walk(in, field, inner)
+				return
+			}
+			fields = append(fields, statuserror.NewErrorField(in, field, err.Error()))
+		}
+	}
+
+	walk("", "", err)
+	return fields
+}
+
+// joinFieldPath 拼接为 prop.slice[2].a 形式
+func joinFieldPath(prefix string, pointer []string) string {
+	b := strings.Builder{}
+	b.WriteString(prefix)
+	for _, p := range pointer {
+		if p != "" && strings.Trim(p, "0123456789") == "" {
+			b.WriteString("[" + p + "]")
+			continue
+		}
+		if b.Len() > 0 {
+			b.WriteString(".")
+		}
+		b.WriteString(p)
+	}
+	return b.String()
+}
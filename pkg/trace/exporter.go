@@ -2,46 +2,276 @@ package trace
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"strings"
+	"time"
 
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
 	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
 	"go.opentelemetry.io/otel/sdk/trace"
+	"google.golang.org/grpc/credentials"
 )
 
+const (
+	protocolGRPC         = "grpc"
+	protocolHTTPProtobuf = "http/protobuf"
+	compressionGzip      = "gzip"
+)
+
+// Headers 支持 "k1=v1,k2=v2" 形式的环境变量配置
+type Headers map[string]string
+
+func (h Headers) MarshalText() ([]byte, error) {
+	parts := make([]string, 0, len(h))
+	for k, v := range h {
+		parts = append(parts, k+"="+v)
+	}
+	return []byte(strings.Join(parts, ",")), nil
+}
+
+func (h *Headers) UnmarshalText(data []byte) error {
+	headers := Headers{}
+	for _, part := range strings.Split(string(data), ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		k, v, ok := strings.Cut(part, "=")
+		if !ok {
+			return fmt.Errorf("invalid header %q, expect key=value", part)
+		}
+		headers[strings.TrimSpace(k)] = strings.TrimSpace(v)
+	}
+	*h = headers
+	return nil
+}
+
+// otlpConnection span 及 metric exporter 共用的连接配置, 由 Trace 配置解析一次后映射为各 exporter 的选项
+type otlpConnection struct {
+	endpoint string
+	// urlPath 仅用于 http traces
+	urlPath  string
+	insecure bool
+	tls      *tls.Config
+	headers  map[string]string
+	gzip     bool
+	timeout  time.Duration
+	// retry 为 nil 时使用 exporter 默认的重试策略
+	retry *otlptracehttp.RetryConfig
+}
+
+func (c *Trace) otlpConnection() (*otlpConnection, error) {
+	tlsConfig, err := c.tlsConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	conn := &otlpConnection{
+		endpoint: c.OTLPEndpoint,
+		urlPath:  c.URLPath,
+		insecure: c.Insecure,
+		tls:      tlsConfig,
+		headers:  c.headers(),
+		gzip:     strings.ToLower(c.Compression) == compressionGzip,
+		timeout:  time.Duration(c.Timeout),
+	}
+	if retry, ok := c.retryConfig(); ok {
+		conn.retry = &retry
+	}
+	return conn, nil
+}
+
 func (c *Trace) newOTLPExporter() (trace.SpanExporter, error) {
-	if c.Insecure {
-		return c.newInsecureOTLPExporter()
+	conn, err := c.otlpConnection()
+	if err != nil {
+		return nil, err
+	}
+
+	switch strings.ToLower(c.Protocol) {
+	case protocolGRPC:
+		return otlptracegrpc.New(context.Background(), conn.traceGRPCOptions()...)
+	case protocolHTTPProtobuf:
+		return otlptracehttp.New(context.Background(), conn.traceHTTPOptions()...)
+	}
+	return nil, fmt.Errorf("unsupported otlp protocol %q", c.Protocol)
+}
+
+func (c *Trace) newOTLPMetricExporter() (sdkmetric.Exporter, error) {
+	conn, err := c.otlpConnection()
+	if err != nil {
+		return nil, err
+	}
+
+	switch strings.ToLower(c.Protocol) {
+	case protocolGRPC:
+		return otlpmetricgrpc.New(context.Background(), conn.metricGRPCOptions()...)
+	case protocolHTTPProtobuf:
+		return otlpmetrichttp.New(context.Background(), conn.metricHTTPOptions()...)
+	}
+	return nil, fmt.Errorf("unsupported otlp protocol %q", c.Protocol)
+}
+
+func (conn *otlpConnection) traceHTTPOptions() []otlptracehttp.Option {
+	opts := []otlptracehttp.Option{otlptracehttp.WithEndpoint(conn.endpoint)}
+	if conn.insecure {
+		opts = append(opts, otlptracehttp.WithInsecure())
+	} else if conn.tls != nil {
+		opts = append(opts, otlptracehttp.WithTLSClientConfig(conn.tls))
+	}
+	if conn.urlPath != "" {
+		opts = append(opts, otlptracehttp.WithURLPath(conn.urlPath))
+	}
+	if len(conn.headers) > 0 {
+		opts = append(opts, otlptracehttp.WithHeaders(conn.headers))
+	}
+	if conn.gzip {
+		opts = append(opts, otlptracehttp.WithCompression(otlptracehttp.GzipCompression))
+	}
+	if conn.timeout > 0 {
+		opts = append(opts, otlptracehttp.WithTimeout(conn.timeout))
+	}
+	if conn.retry != nil {
+		opts = append(opts, otlptracehttp.WithRetry(*conn.retry))
+	}
+	return opts
+}
+
+func (conn *otlpConnection) traceGRPCOptions() []otlptracegrpc.Option {
+	opts := []otlptracegrpc.Option{otlptracegrpc.WithEndpoint(conn.endpoint)}
+	if conn.insecure {
+		opts = append(opts, otlptracegrpc.WithInsecure())
+	} else if conn.tls != nil {
+		opts = append(opts, otlptracegrpc.WithTLSCredentials(credentials.NewTLS(conn.tls)))
+	}
+	if len(conn.headers) > 0 {
+		opts = append(opts, otlptracegrpc.WithHeaders(conn.headers))
+	}
+	if conn.gzip {
+		opts = append(opts, otlptracegrpc.WithCompressor(compressionGzip))
+	}
+	if conn.timeout > 0 {
+		opts = append(opts, otlptracegrpc.WithTimeout(conn.timeout))
 	}
-	return c.newSecureOTLPExporter()
+	if conn.retry != nil {
+		opts = append(opts, otlptracegrpc.WithRetry(otlptracegrpc.RetryConfig(*conn.retry)))
+	}
+	return opts
 }
 
-func (c *Trace) newInsecureOTLPExporter() (trace.SpanExporter, error) {
-	if c.AccessToken == "" {
-		return otlptracehttp.New(context.Background(),
-			otlptracehttp.WithEndpoint(c.OTLPEndpoint),
-			otlptracehttp.WithInsecure(),
-		)
+func (conn *otlpConnection) metricHTTPOptions() []otlpmetrichttp.Option {
+	opts := []otlpmetrichttp.Option{otlpmetrichttp.WithEndpoint(conn.endpoint)}
+	if conn.insecure {
+		opts = append(opts, otlpmetrichttp.WithInsecure())
+	} else if conn.tls != nil {
+		opts = append(opts, otlpmetrichttp.WithTLSClientConfig(conn.tls))
+	}
+	if len(conn.headers) > 0 {
+		opts = append(opts, otlpmetrichttp.WithHeaders(conn.headers))
+	}
+	if conn.gzip {
+		opts = append(opts, otlpmetrichttp.WithCompression(otlpmetrichttp.GzipCompression))
+	}
+	if conn.timeout > 0 {
+		opts = append(opts, otlpmetrichttp.WithTimeout(conn.timeout))
 	}
+	if conn.retry != nil {
+		opts = append(opts, otlpmetrichttp.WithRetry(otlpmetrichttp.RetryConfig(*conn.retry)))
+	}
+	return opts
+}
 
-	return otlptracehttp.New(context.Background(),
-		otlptracehttp.WithEndpoint(c.OTLPEndpoint),
-		otlptracehttp.WithInsecure(),
-		otlptracehttp.WithHeaders(map[string]string{
-			"Authorization": c.AccessToken,
-		}),
-	)
+func (conn *otlpConnection) metricGRPCOptions() []otlpmetricgrpc.Option {
+	opts := []otlpmetricgrpc.Option{otlpmetricgrpc.WithEndpoint(conn.endpoint)}
+	if conn.insecure {
+		opts = append(opts, otlpmetricgrpc.WithInsecure())
+	} else if conn.tls != nil {
+		opts = append(opts, otlpmetricgrpc.WithTLSCredentials(credentials.NewTLS(conn.tls)))
+	}
+	if len(conn.headers) > 0 {
+		opts = append(opts, otlpmetricgrpc.WithHeaders(conn.headers))
+	}
+	if conn.gzip {
+		opts = append(opts, otlpmetricgrpc.WithCompressor(compressionGzip))
+	}
+	if conn.timeout > 0 {
+		opts = append(opts, otlpmetricgrpc.WithTimeout(conn.timeout))
+	}
+	if conn.retry != nil {
+		opts = append(opts, otlpmetricgrpc.WithRetry(otlpmetricgrpc.RetryConfig(*conn.retry)))
+	}
+	return opts
 }
 
-func (c *Trace) newSecureOTLPExporter() (trace.SpanExporter, error) {
-	if c.AccessToken == "" {
-		return otlptracehttp.New(context.Background(),
-			otlptracehttp.WithEndpoint(c.OTLPEndpoint),
-		)
+// headers Headers 与 AccessToken 合并, AccessToken 作为 Authorization
+func (c *Trace) headers() map[string]string {
+	headers := make(map[string]string, len(c.Headers)+1)
+	for k, v := range c.Headers {
+		headers[k] = v
+	}
+	if c.AccessToken != "" {
+		headers["Authorization"] = c.AccessToken
+	}
+	return headers
+}
+
+// retryConfig 未配置任何重试参数时使用 exporter 默认的重试策略
+func (c *Trace) retryConfig() (otlptracehttp.RetryConfig, bool) {
+	if !c.DisableRetry && c.RetryInitialInterval == 0 && c.RetryMaxInterval == 0 && c.RetryMaxElapsedTime == 0 {
+		return otlptracehttp.RetryConfig{}, false
+	}
+
+	// 与 exporter 默认值一致
+	retry := otlptracehttp.RetryConfig{
+		Enabled:         !c.DisableRetry,
+		InitialInterval: 5 * time.Second,
+		MaxInterval:     30 * time.Second,
+		MaxElapsedTime:  time.Minute,
+	}
+	if c.RetryInitialInterval > 0 {
+		retry.InitialInterval = time.Duration(c.RetryInitialInterval)
+	}
+	if c.RetryMaxInterval > 0 {
+		retry.MaxInterval = time.Duration(c.RetryMaxInterval)
+	}
+	if c.RetryMaxElapsedTime > 0 {
+		retry.MaxElapsedTime = time.Duration(c.RetryMaxElapsedTime)
+	}
+	return retry, true
+}
+
+// tlsConfig 未配置 TLSCAFile 及客户端证书时返回 nil, 使用系统证书
+func (c *Trace) tlsConfig() (*tls.Config, error) {
+	if c.Insecure || (c.TLSCAFile == "" && c.TLSCertFile == "" && c.TLSKeyFile == "") {
+		return nil, nil
+	}
+
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	if c.TLSCAFile != "" {
+		ca, err := os.ReadFile(c.TLSCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("read otlp ca file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(ca) {
+			return nil, fmt.Errorf("no certificate found in otlp ca file %s", c.TLSCAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if c.TLSCertFile != "" || c.TLSKeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(c.TLSCertFile, c.TLSKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("load otlp client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
 	}
 
-	return otlptracehttp.New(context.Background(),
-		otlptracehttp.WithEndpoint(c.OTLPEndpoint),
-		otlptracehttp.WithHeaders(map[string]string{
-			"Authorization": c.AccessToken,
-		}),
-	)
+	return tlsConfig, nil
 }
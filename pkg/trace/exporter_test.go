@@ -0,0 +1,91 @@
+package trace
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestOTLPConnection(t *testing.T) {
+	cases := []struct {
+		name  string
+		trace Trace
+		check func(t *testing.T, conn *otlpConnection)
+	}{
+		{
+			name:  "defaults",
+			trace: Trace{OTLPEndpoint: "collector:4317"},
+			check: func(t *testing.T, conn *otlpConnection) {
+				if conn.endpoint != "collector:4317" || conn.insecure || conn.tls != nil || conn.gzip || conn.retry != nil || len(conn.headers) != 0 {
+					t.Fatalf("unexpected connection %+v", conn)
+				}
+			},
+		},
+		{
+			name:  "headers with access token",
+			trace: Trace{Headers: Headers{"X-Tenant": "a"}, AccessToken: "token"},
+			check: func(t *testing.T, conn *otlpConnection) {
+				if want := map[string]string{"X-Tenant": "a", "Authorization": "token"}; !reflect.DeepEqual(conn.headers, want) {
+					t.Fatalf("headers = %v, want %v", conn.headers, want)
+				}
+			},
+		},
+		{
+			name:  "gzip and timeout",
+			trace: Trace{Compression: "GZIP", Timeout: Duration(3 * time.Second), URLPath: "/otlp/v1/traces"},
+			check: func(t *testing.T, conn *otlpConnection) {
+				if !conn.gzip || conn.timeout != 3*time.Second || conn.urlPath != "/otlp/v1/traces" {
+					t.Fatalf("unexpected connection %+v", conn)
+				}
+			},
+		},
+		{
+			name:  "insecure ignores tls files",
+			trace: Trace{Insecure: true, TLSCAFile: "missing.pem"},
+			check: func(t *testing.T, conn *otlpConnection) {
+				if !conn.insecure || conn.tls != nil {
+					t.Fatalf("unexpected connection %+v", conn)
+				}
+			},
+		},
+		{
+			name:  "retry",
+			trace: Trace{RetryMaxInterval: Duration(time.Second)},
+			check: func(t *testing.T, conn *otlpConnection) {
+				if conn.retry == nil || !conn.retry.Enabled || conn.retry.MaxInterval != time.Second || conn.retry.InitialInterval != 5*time.Second {
+					t.Fatalf("unexpected retry %+v", conn.retry)
+				}
+			},
+		},
+		{
+			name:  "disable retry",
+			trace: Trace{DisableRetry: true},
+			check: func(t *testing.T, conn *otlpConnection) {
+				if conn.retry == nil || conn.retry.Enabled {
+					t.Fatalf("unexpected retry %+v", conn.retry)
+				}
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			conn, err := c.trace.otlpConnection()
+			if err != nil {
+				t.Fatal(err)
+			}
+			c.check(t, conn)
+
+			// 同一连接配置可映射为所有 exporter 的选项
+			for _, n := range []int{len(conn.traceHTTPOptions()), len(conn.traceGRPCOptions()), len(conn.metricHTTPOptions()), len(conn.metricGRPCOptions())} {
+				if n == 0 {
+					t.Fatal("missing exporter options")
+				}
+			}
+		})
+	}
+
+	if _, err := (&Trace{TLSCAFile: "missing.pem"}).otlpConnection(); err == nil {
+		t.Fatal("expect error for missing ca file")
+	}
+}
@@ -0,0 +1,103 @@
+package trace
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/exemplar"
+)
+
+// Metric OTel MeterProvider, 作为 Trace.Metric 配置, 由 Trace.Init 创建,
+// 与 Trace 使用相同的 OTLP 连接配置及 resource, 采样的 span 中记录的指标附带 trace exemplar
+type Metric struct {
+	// Enabled 默认关闭
+	Enabled bool `env:""`
+	// 导出间隔, 默认 60s
+	ExportInterval Duration `env:""`
+
+	mp *sdkmetric.MeterProvider
+}
+
+func (c *Metric) SetDefaults() {
+	if c.ExportInterval == 0 {
+		c.ExportInterval = Duration(time.Minute)
+	}
+}
+
+func (c *Metric) init(t *Trace) error {
+	c.SetDefaults()
+
+	if t.ServiceName == "" {
+		return errors.New("trace: ServiceName is required for metrics")
+	}
+
+	exporter, err := t.newOTLPMetricExporter()
+	if err != nil {
+		return err
+	}
+
+	c.mp = sdkmetric.NewMeterProvider(
+		sdkmetric.WithReader(sdkmetric.NewPeriodicReader(exporter, sdkmetric.WithInterval(time.Duration(c.ExportInterval)))),
+		sdkmetric.WithResource(newResource(t.ServiceName)),
+		sdkmetric.WithExemplarFilter(exemplar.TraceBasedFilter),
+	)
+	otel.SetMeterProvider(c.mp)
+	return nil
+}
+
+// ForceFlush 立即导出已记录的指标
+func (c *Metric) ForceFlush(ctx context.Context) error {
+	if c.mp == nil {
+		return nil
+	}
+	return c.mp.ForceFlush(ctx)
+}
+
+// Shutdown 导出剩余的指标并关闭 MeterProvider
+func (c *Metric) Shutdown(ctx context.Context) error {
+	if c.mp == nil {
+		return nil
+	}
+	return c.mp.Shutdown(ctx)
+}
+
+// http.server.request.duration 按 semantic conventions 推荐的分桶
+var httpServerDurationBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.075, 0.1, 0.25, 0.5, 0.75, 1, 2.5, 5, 7.5, 10}
+
+var (
+	httpServerDuration     metric.Float64Histogram
+	httpServerDurationOnce sync.Once
+)
+
+// RecordHTTPServerRequest 记录 http.server.request.duration,
+// ctx 中的 span 被采样时附带 exemplar, 未调用 Metric.Init 时为 noop
+func RecordHTTPServerRequest(ctx context.Context, method string, route string, scheme string, statusCode int, duration time.Duration) {
+	httpServerDurationOnce.Do(func() {
+		// 全局 MeterProvider 设置后, 已创建的 instrument 会委托到新的 provider
+		httpServerDuration, _ = otel.Meter("github.com/kunlun-qilian/confserver").Float64Histogram(
+			"http.server.request.duration",
+			metric.WithUnit("s"),
+			metric.WithDescription("Duration of HTTP server requests."),
+			metric.WithExplicitBucketBoundaries(httpServerDurationBuckets...),
+		)
+	})
+	if httpServerDuration == nil {
+		return
+	}
+
+	attrs := []attribute.KeyValue{
+		attribute.String("http.request.method", method),
+		attribute.String("url.scheme", scheme),
+		attribute.Int("http.response.status_code", statusCode),
+	}
+	if route != "" {
+		attrs = append(attrs, attribute.String("http.route", route))
+	}
+	httpServerDuration.Record(ctx, duration.Seconds(), metric.WithAttributes(attrs...))
+}
@@ -0,0 +1,57 @@
+package trace
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMetricUsesTraceConfig(t *testing.T) {
+	requests := make(chan *http.Request, 16)
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests <- r
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer collector.Close()
+
+	c := &Trace{
+		OTLPEndpoint: strings.TrimPrefix(collector.URL, "http://"),
+		Insecure:     true,
+		AccessToken:  "token",
+		ServiceName:  "srv-test",
+		Metric:       Metric{Enabled: true},
+	}
+	c.Init()
+	defer c.Shutdown(context.Background())
+
+	RecordHTTPServerRequest(context.Background(), http.MethodGet, "/users/:id", "http", http.StatusOK, 10*time.Millisecond)
+	if err := c.ForceFlush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	for {
+		select {
+		case r := <-requests:
+			if r.URL.Path != "/v1/metrics" {
+				continue
+			}
+			if got := r.Header.Get("Authorization"); got != "token" {
+				t.Fatalf("Authorization = %q", got)
+			}
+			return
+		case <-time.After(2 * time.Second):
+			t.Fatal("metrics not exported")
+		}
+	}
+}
+
+func TestMetricRequiresServiceName(t *testing.T) {
+	c := &Trace{Metric: Metric{Enabled: true}}
+	c.SetDefaults()
+	if err := c.Metric.init(c); err == nil {
+		t.Fatal("expect error without ServiceName")
+	}
+}
@@ -0,0 +1,200 @@
+package trace
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+const (
+	samplerAlwaysOn     = "always_on"
+	samplerAlwaysOff    = "always_off"
+	samplerTraceIDRatio = "traceidratio"
+	samplerRateLimited  = "ratelimited"
+	samplerParentBased  = "parentbased_"
+)
+
+// SamplingRule 按请求路径的采样规则, Pattern 以 * 结尾时按前缀匹配, 否则按 path.Match 匹配
+type SamplingRule struct {
+	Pattern string
+	Ratio   float64
+}
+
+func (r SamplingRule) match(p string) bool {
+	if prefix, ok := strings.CutSuffix(r.Pattern, "*"); ok && !strings.ContainsAny(prefix, "*?[") {
+		return strings.HasPrefix(p, prefix)
+	}
+	matched, _ := path.Match(r.Pattern, p)
+	return matched
+}
+
+// SamplingRules 支持 "/metrics=0,/api/payments/*=1" 形式的环境变量配置, 按顺序匹配第一条
+type SamplingRules []SamplingRule
+
+func (rules SamplingRules) MarshalText() ([]byte, error) {
+	parts := make([]string, len(rules))
+	for i, r := range rules {
+		parts[i] = r.Pattern + "=" + strconv.FormatFloat(r.Ratio, 'f', -1, 64)
+	}
+	return []byte(strings.Join(parts, ",")), nil
+}
+
+func (rules *SamplingRules) UnmarshalText(data []byte) error {
+	*rules = nil
+	for _, part := range strings.Split(string(data), ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		pattern, ratio, ok := strings.Cut(part, "=")
+		if !ok {
+			return fmt.Errorf("invalid sampling rule %q, expect pattern=ratio", part)
+		}
+		r, err := strconv.ParseFloat(strings.TrimSpace(ratio), 64)
+		if err != nil || r < 0 || r > 1 {
+			return fmt.Errorf("invalid sampling ratio of rule %q, expect 0 ~ 1", part)
+		}
+		*rules = append(*rules, SamplingRule{Pattern: strings.TrimSpace(pattern), Ratio: r})
+	}
+	return nil
+}
+
+// newSampler 按 Sampler 名称创建, 名称与 OTEL_TRACES_SAMPLER 一致, 额外支持 ratelimited / parentbased_ratelimited
+func (c *Trace) newSampler() (sdktrace.Sampler, error) {
+	name := strings.ToLower(c.Sampler)
+
+	root, err := c.newRootSampler(strings.TrimPrefix(name, samplerParentBased))
+	if err != nil {
+		return nil, err
+	}
+
+	sampler := root
+	if strings.HasPrefix(name, samplerParentBased) {
+		sampler = sdktrace.ParentBased(root)
+	}
+
+	if len(c.SamplingRules) > 0 {
+		sampler = newRuleBasedSampler(c.SamplingRules, sampler)
+	}
+	return sampler, nil
+}
+
+func (c *Trace) newRootSampler(name string) (sdktrace.Sampler, error) {
+	switch name {
+	case samplerAlwaysOn:
+		return sdktrace.AlwaysSample(), nil
+	case samplerAlwaysOff:
+		return sdktrace.NeverSample(), nil
+	case samplerTraceIDRatio:
+		// 未配置时为 0, 会丢弃所有 trace, 需使用 always_off 明确关闭
+		if c.SamplerRatio <= 0 || c.SamplerRatio > 1 {
+			return nil, fmt.Errorf("invalid SamplerRatio %g for sampler %q, expect (0, 1]", c.SamplerRatio, c.Sampler)
+		}
+		return sdktrace.TraceIDRatioBased(c.SamplerRatio), nil
+	case samplerRateLimited:
+		if c.SamplerRateLimit <= 0 {
+			return nil, fmt.Errorf("invalid SamplerRateLimit %g for sampler %q, expect > 0", c.SamplerRateLimit, c.Sampler)
+		}
+		return newRateLimitedSampler(c.SamplerRateLimit), nil
+	}
+	return nil, fmt.Errorf("unsupported sampler %q", c.Sampler)
+}
+
+// ruleBasedSampler 匹配到规则时按规则的比例采样, 优先于父 span 的采样结果, 否则交给 fallback
+type ruleBasedSampler struct {
+	rules    []SamplingRule
+	samplers []sdktrace.Sampler
+	fallback sdktrace.Sampler
+}
+
+func newRuleBasedSampler(rules SamplingRules, fallback sdktrace.Sampler) sdktrace.Sampler {
+	s := &ruleBasedSampler{
+		rules:    rules,
+		samplers: make([]sdktrace.Sampler, len(rules)),
+		fallback: fallback,
+	}
+	for i, r := range rules {
+		s.samplers[i] = sdktrace.TraceIDRatioBased(r.Ratio)
+	}
+	return s
+}
+
+func (s *ruleBasedSampler) ShouldSample(p sdktrace.SamplingParameters) sdktrace.SamplingResult {
+	// 优先使用请求的实际路径, 其次为 span 名称 (gin 路由)
+	requestPath := ""
+	for _, attr := range p.Attributes {
+		if attr.Key == "http.request.path" {
+			requestPath = attr.Value.AsString()
+			break
+		}
+	}
+
+	for i, r := range s.rules {
+		if (requestPath != "" && r.match(requestPath)) || r.match(p.Name) {
+			return s.samplers[i].ShouldSample(p)
+		}
+	}
+	return s.fallback.ShouldSample(p)
+}
+
+func (s *ruleBasedSampler) Description() string {
+	rules, _ := SamplingRules(s.rules).MarshalText()
+	return fmt.Sprintf("RuleBased{%s}->%s", rules, s.fallback.Description())
+}
+
+// rateLimitedSampler 令牌桶, 每秒最多采样 limit 个 span
+type rateLimitedSampler struct {
+	limit float64
+
+	mu       sync.Mutex
+	tokens   float64
+	lastTick time.Time
+}
+
+func newRateLimitedSampler(limit float64) sdktrace.Sampler {
+	return &rateLimitedSampler{
+		limit:    limit,
+		tokens:   limit,
+		lastTick: time.Now(),
+	}
+}
+
+func (s *rateLimitedSampler) allow() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.tokens += now.Sub(s.lastTick).Seconds() * s.limit
+	// 桶容量为 1 秒的配额, 至少 1 个
+	if burst := max(s.limit, 1); s.tokens > burst {
+		s.tokens = burst
+	}
+	s.lastTick = now
+
+	if s.tokens < 1 {
+		return false
+	}
+	s.tokens--
+	return true
+}
+
+func (s *rateLimitedSampler) ShouldSample(p sdktrace.SamplingParameters) sdktrace.SamplingResult {
+	decision := sdktrace.Drop
+	if s.allow() {
+		decision = sdktrace.RecordAndSample
+	}
+	return sdktrace.SamplingResult{
+		Decision:   decision,
+		Tracestate: oteltrace.SpanContextFromContext(p.ParentContext).TraceState(),
+	}
+}
+
+func (s *rateLimitedSampler) Description() string {
+	return fmt.Sprintf("RateLimited{%g}", s.limit)
+}
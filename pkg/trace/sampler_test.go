@@ -0,0 +1,169 @@
+package trace
+
+import (
+	"context"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+func samplingParameters(ctx context.Context, name string, path string) sdktrace.SamplingParameters {
+	p := sdktrace.SamplingParameters{
+		ParentContext: ctx,
+		TraceID:       oteltrace.TraceID{0x01},
+		Name:          name,
+	}
+	if path != "" {
+		p.Attributes = []attribute.KeyValue{attribute.String("http.request.path", path)}
+	}
+	return p
+}
+
+func sampledParent(sampled bool) context.Context {
+	flags := oteltrace.TraceFlags(0)
+	if sampled {
+		flags = oteltrace.FlagsSampled
+	}
+	return oteltrace.ContextWithRemoteSpanContext(context.Background(), oteltrace.NewSpanContext(oteltrace.SpanContextConfig{
+		TraceID:    oteltrace.TraceID{0x01},
+		SpanID:     oteltrace.SpanID{0x01},
+		TraceFlags: flags,
+		Remote:     true,
+	}))
+}
+
+func TestNewSampler(t *testing.T) {
+	cases := []struct {
+		name    string
+		trace   Trace
+		ctx     context.Context
+		span    string
+		path    string
+		sampled bool
+		err     bool
+	}{
+		{name: "default samples root", trace: Trace{}, ctx: context.Background(), sampled: true},
+		{name: "default follows unsampled parent", trace: Trace{}, ctx: sampledParent(false), sampled: false},
+		{name: "always sample ignores parent", trace: Trace{AlwaysSample: true}, ctx: sampledParent(false), sampled: true},
+		{name: "always_off", trace: Trace{Sampler: "always_off"}, ctx: context.Background(), sampled: false},
+		{name: "ratio 1", trace: Trace{Sampler: "traceidratio", SamplerRatio: 1}, ctx: context.Background(), sampled: true},
+		{name: "ratio unset", trace: Trace{Sampler: "traceidratio"}, err: true},
+		{name: "ratio out of range", trace: Trace{Sampler: "traceidratio", SamplerRatio: 1.5}, err: true},
+		{name: "parent based ratio follows sampled parent", trace: Trace{Sampler: "parentbased_traceidratio", SamplerRatio: 0.0001}, ctx: sampledParent(true), sampled: true},
+		{name: "parent based always_off root", trace: Trace{Sampler: "parentbased_always_off"}, ctx: context.Background(), sampled: false},
+		{name: "rate limited", trace: Trace{Sampler: "ratelimited", SamplerRateLimit: 10}, ctx: context.Background(), sampled: true},
+		{name: "rate limit unset", trace: Trace{Sampler: "ratelimited"}, err: true},
+		{name: "unknown", trace: Trace{Sampler: "sometimes"}, err: true},
+		{
+			name:    "rule never samples path",
+			trace:   Trace{Sampler: "always_on", SamplingRules: SamplingRules{{Pattern: "/metrics", Ratio: 0}}},
+			ctx:     context.Background(),
+			path:    "/metrics",
+			sampled: false,
+		},
+		{
+			name:    "rule overrides sampled parent",
+			trace:   Trace{SamplingRules: SamplingRules{{Pattern: "/metrics", Ratio: 0}}},
+			ctx:     sampledParent(true),
+			path:    "/metrics",
+			sampled: false,
+		},
+		{
+			name:    "rule prefix samples all",
+			trace:   Trace{Sampler: "always_off", SamplingRules: SamplingRules{{Pattern: "/api/payments/*", Ratio: 1}}},
+			ctx:     context.Background(),
+			path:    "/api/payments/1/refund",
+			sampled: true,
+		},
+		{
+			name:    "rule matches span name",
+			trace:   Trace{Sampler: "always_off", SamplingRules: SamplingRules{{Pattern: "/users/:id", Ratio: 1}}},
+			ctx:     context.Background(),
+			span:    "/users/:id",
+			path:    "/users/1",
+			sampled: true,
+		},
+		{
+			name:    "no rule matched falls back",
+			trace:   Trace{Sampler: "always_off", SamplingRules: SamplingRules{{Pattern: "/api/payments/*", Ratio: 1}}},
+			ctx:     context.Background(),
+			path:    "/api/orders/1",
+			sampled: false,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			conf := c.trace
+			conf.SetDefaults()
+
+			sampler, err := conf.newSampler()
+			if c.err {
+				if err == nil {
+					t.Fatalf("expect error, got sampler %s", sampler.Description())
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			result := sampler.ShouldSample(samplingParameters(c.ctx, c.span, c.path))
+			if sampled := result.Decision == sdktrace.RecordAndSample; sampled != c.sampled {
+				t.Fatalf("%s sampled = %v, want %v", sampler.Description(), sampled, c.sampled)
+			}
+		})
+	}
+}
+
+func TestRateLimitedSampler(t *testing.T) {
+	sampler := newRateLimitedSampler(5)
+
+	sampled := 0
+	for i := 0; i < 100; i++ {
+		if sampler.ShouldSample(samplingParameters(context.Background(), "", "")).Decision == sdktrace.RecordAndSample {
+			sampled++
+		}
+	}
+	if sampled != 5 {
+		t.Fatalf("sampled = %d, want 5", sampled)
+	}
+}
+
+func TestSamplingRulesText(t *testing.T) {
+	cases := []struct {
+		text  string
+		rules SamplingRules
+		err   bool
+	}{
+		{text: "", rules: nil},
+		{text: "/metrics=0, /api/payments/*=1", rules: SamplingRules{{Pattern: "/metrics", Ratio: 0}, {Pattern: "/api/payments/*", Ratio: 1}}},
+		{text: "/a=0.25", rules: SamplingRules{{Pattern: "/a", Ratio: 0.25}}},
+		{text: "/a", err: true},
+		{text: "/a=2", err: true},
+		{text: "/a=x", err: true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.text, func(t *testing.T) {
+			var rules SamplingRules
+			err := rules.UnmarshalText([]byte(c.text))
+			if (err != nil) != c.err {
+				t.Fatalf("err = %v", err)
+			}
+			if c.err {
+				return
+			}
+			if len(rules) != len(c.rules) {
+				t.Fatalf("rules = %v, want %v", rules, c.rules)
+			}
+			for i := range rules {
+				if rules[i] != c.rules[i] {
+					t.Fatalf("rules = %v, want %v", rules, c.rules)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,48 @@
+package trace
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+type provider interface {
+	ForceFlush(ctx context.Context) error
+	Shutdown(ctx context.Context) error
+}
+
+var (
+	providersMu sync.Mutex
+	providers   []provider
+)
+
+func registerProvider(p provider) {
+	providersMu.Lock()
+	defer providersMu.Unlock()
+	providers = append(providers, p)
+}
+
+// ForceFlush 导出所有已 Init 的 Trace 中缓存的数据
+func ForceFlush(ctx context.Context) error {
+	providersMu.Lock()
+	defer providersMu.Unlock()
+
+	var errs []error
+	for _, p := range providers {
+		errs = append(errs, p.ForceFlush(ctx))
+	}
+	return errors.Join(errs...)
+}
+
+// Shutdown 按 Init 的顺序关闭所有已 Init 的 Trace, 进程退出前调用以免丢失缓存的数据
+func Shutdown(ctx context.Context) error {
+	providersMu.Lock()
+	defer providersMu.Unlock()
+
+	var errs []error
+	for _, p := range providers {
+		errs = append(errs, p.Shutdown(ctx))
+	}
+	providers = nil
+	return errors.Join(errs...)
+}
@@ -0,0 +1,108 @@
+package trace
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel"
+)
+
+type fakeProvider struct {
+	name  string
+	calls *[]string
+	err   error
+}
+
+func (p *fakeProvider) ForceFlush(ctx context.Context) error {
+	*p.calls = append(*p.calls, "flush "+p.name)
+	return p.err
+}
+
+func (p *fakeProvider) Shutdown(ctx context.Context) error {
+	*p.calls = append(*p.calls, "shutdown "+p.name)
+	return p.err
+}
+
+func TestShutdownProviders(t *testing.T) {
+	_ = Shutdown(context.Background())
+
+	var calls []string
+	errB := errors.New("b failed")
+	registerProvider(&fakeProvider{name: "a", calls: &calls})
+	registerProvider(&fakeProvider{name: "b", calls: &calls, err: errB})
+
+	if err := ForceFlush(context.Background()); !errors.Is(err, errB) {
+		t.Fatalf("ForceFlush err = %v", err)
+	}
+	if err := Shutdown(context.Background()); !errors.Is(err, errB) {
+		t.Fatalf("Shutdown err = %v", err)
+	}
+	// 已关闭的 provider 不再重复关闭
+	if err := Shutdown(context.Background()); err != nil {
+		t.Fatalf("second Shutdown err = %v", err)
+	}
+
+	want := "flush a,flush b,shutdown a,shutdown b"
+	if got := strings.Join(calls, ","); got != want {
+		t.Fatalf("calls = %s, want %s", got, want)
+	}
+}
+
+func TestTraceForceFlushAndShutdown(t *testing.T) {
+	paths := make(chan string, 16)
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths <- r.URL.Path
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer collector.Close()
+
+	prev := otel.GetTracerProvider()
+	defer otel.SetTracerProvider(prev)
+
+	c := &Trace{
+		OTLPEndpoint: strings.TrimPrefix(collector.URL, "http://"),
+		Insecure:     true,
+		ServiceName:  "srv-test",
+	}
+	// 未 Init 时为 noop
+	if err := c.ForceFlush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	c.Init()
+
+	exported := func() bool {
+		select {
+		case p := <-paths:
+			return p == "/v1/traces"
+		case <-time.After(2 * time.Second):
+			return false
+		}
+	}
+
+	_, span := otel.Tracer("test").Start(context.Background(), "flush")
+	span.End()
+	if err := c.ForceFlush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if !exported() {
+		t.Fatal("ForceFlush did not export span")
+	}
+
+	_, span = otel.Tracer("test").Start(context.Background(), "shutdown")
+	span.End()
+	if err := Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if !exported() {
+		t.Fatal("Shutdown did not export span")
+	}
+}
@@ -2,6 +2,7 @@ package trace
 
 import (
 	"context"
+	"errors"
 	"net/http"
 	"strings"
 	"time"
@@ -16,9 +17,10 @@ import (
 )
 
 const (
-	defaultOTLPEndpoint = "otel-collector.observability:4318"
-	propagatorB3        = "b3"
-	propagatorW3C       = "w3c"
+	defaultOTLPEndpoint     = "otel-collector.observability:4318"
+	defaultOTLPGRPCEndpoint = "otel-collector.observability:4317"
+	propagatorB3            = "b3"
+	propagatorW3C           = "w3c"
 )
 
 var ServiceName string
@@ -26,25 +28,74 @@ var ServiceName string
 type Trace struct {
 	// OTLPEndpoint OTEL Collector / Gateway 地址，留空默认访问本集群的 OTEL Collector
 	OTLPEndpoint string `env:""`
-	// AlwaysSample default false
+	// AlwaysSample default false, 等同于 Sampler=always_on
 	AlwaysSample bool `env:""`
+	// Sampler always_on / always_off / traceidratio / ratelimited, 加 parentbased_ 前缀时根 span 使用该采样器,
+	// 其余跟随父 span, 默认 parentbased_always_on
+	Sampler string `env:""`
+	// SamplerRatio traceidratio 的采样比例 (0, 1], 使用 traceidratio 时必须配置
+	SamplerRatio float64 `env:""`
+	// SamplerRateLimit ratelimited 每秒最多采样的 span 数, 使用 ratelimited 时必须配置
+	SamplerRateLimit float64 `env:""`
+	// SamplingRules 按请求路径的采样规则, 如 "/metrics=0,/api/payments/*=1", 优先于 Sampler
+	SamplingRules SamplingRules `env:""`
 	// 默认启用证书，关闭证书设置为true
 	Insecure bool `env:""`
 	// AccessToken 访问 OTEL 网关的 access token, 可以为空
 	AccessToken string `env:""`
+	// Protocol 支持 grpc / http/protobuf, 默认 http/protobuf
+	Protocol string `env:""`
+	// Headers 导出时附带的请求头, 如 "X-Scope-OrgID=tenant"
+	Headers Headers `env:""`
+	// Compression 支持 gzip, 默认不压缩
+	Compression string `env:""`
+	// Timeout 单次导出超时, 默认 10s
+	Timeout Duration `env:""`
+	// 导出失败时的重试退避, 默认 5s / 30s / 1m
+	DisableRetry         bool     `env:""`
+	RetryInitialInterval Duration `env:""`
+	RetryMaxInterval     Duration `env:""`
+	RetryMaxElapsedTime  Duration `env:""`
+	// URLPath http/protobuf 的路径, 默认 /v1/traces
+	URLPath string `env:""`
+	// Collector 的 CA 证书, 留空使用系统证书
+	TLSCAFile string `env:""`
+	// 客户端证书, Collector 要求 mTLS 时配置
+	TLSCertFile string `env:""`
+	TLSKeyFile  string `env:""`
 	// Propagator 支持 b3 / w3c, 默认 w3c
 	Propagator  string `env:""`
 	ServiceName string `env:""`
+	// Metric OTel 指标, 使用上述相同的 OTLP 连接配置
+	Metric Metric
+
+	tp *sdktrace.TracerProvider
 }
 
 func (c *Trace) SetDefaults() {
+	if c.Protocol == "" {
+		c.Protocol = protocolHTTPProtobuf
+	}
+
 	if c.OTLPEndpoint == "" {
-		c.OTLPEndpoint = defaultOTLPEndpoint
+		if strings.ToLower(c.Protocol) == protocolGRPC {
+			c.OTLPEndpoint = defaultOTLPGRPCEndpoint
+		} else {
+			c.OTLPEndpoint = defaultOTLPEndpoint
+		}
 	}
 
 	if c.Propagator == "" {
 		c.Propagator = "w3c"
 	}
+
+	if c.Sampler == "" {
+		if c.AlwaysSample {
+			c.Sampler = samplerAlwaysOn
+		} else {
+			c.Sampler = samplerParentBased + samplerAlwaysOn
+		}
+	}
 }
 
 func (c *Trace) Init() {
@@ -57,18 +108,41 @@ func (c *Trace) Init() {
 		panic(err)
 	}
 
-	var opts []sdktrace.TracerProviderOption
-	if c.AlwaysSample {
-		opts = append(opts, sdktrace.WithBatcher(exporter), sdktrace.WithSampler(sdktrace.AlwaysSample()),
-			sdktrace.WithResource(newResource(ServiceName)))
-	} else {
-		opts = append(opts, sdktrace.WithBatcher(exporter),
-			sdktrace.WithResource(newResource(ServiceName)))
+	sampler, err := c.newSampler()
+	if err != nil {
+		panic(err)
 	}
 
-	tp := sdktrace.NewTracerProvider(opts...)
-	otel.SetTracerProvider(tp)
+	c.tp = sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithSampler(sampler),
+		sdktrace.WithResource(newResource(ServiceName)),
+	)
+	otel.SetTracerProvider(c.tp)
 	otel.SetTextMapPropagator(c.newPropagator())
+
+	if c.Metric.Enabled {
+		if err := c.Metric.init(c); err != nil {
+			panic(err)
+		}
+	}
+	registerProvider(c)
+}
+
+// ForceFlush 导出 batcher 中缓存的 span 及已记录的指标
+func (c *Trace) ForceFlush(ctx context.Context) error {
+	if c.tp == nil {
+		return nil
+	}
+	return errors.Join(c.tp.ForceFlush(ctx), c.Metric.ForceFlush(ctx))
+}
+
+// Shutdown 导出剩余的 span 及指标并关闭 TracerProvider / MeterProvider
+func (c *Trace) Shutdown(ctx context.Context) error {
+	if c.tp == nil {
+		return nil
+	}
+	return errors.Join(c.tp.Shutdown(ctx), c.Metric.Shutdown(ctx))
 }
 
 func (c *Trace) newPropagator() propagation.TextMapPropagator {
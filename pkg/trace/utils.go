@@ -5,11 +5,32 @@ import (
 	"net"
 	"net/http"
 	"strings"
+	"time"
 
 	"go.opentelemetry.io/otel/trace"
 	oteltrace "go.opentelemetry.io/otel/trace"
 )
 
+// Duration time.Duration 支持 "30s" 形式的环境变量配置
+type Duration time.Duration
+
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+func (d *Duration) UnmarshalText(data []byte) error {
+	if len(data) == 0 {
+		*d = 0
+		return nil
+	}
+	dur, err := time.ParseDuration(string(data))
+	if err != nil {
+		return err
+	}
+	*d = Duration(dur)
+	return nil
+}
+
 func SpanCtxFromContext(ctx context.Context) oteltrace.SpanContext {
 	return oteltrace.SpanContextFromContext(ctx)
 }
@@ -0,0 +1,71 @@
+package confserver
+
+import (
+	"fmt"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/gin-gonic/gin"
+	trace2 "github.com/kunlun-qilian/confserver/pkg/trace"
+	"github.com/kunlun-qilian/confx"
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// RecoveryHandler recover handler 中的 panic, 记录日志及 span 后返回 statuserror 格式的 500
+// 需在 TraceHandler 之后注册以获取当前 span
+func RecoveryHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		defer func() {
+			r := recover()
+			if r == nil {
+				return
+			}
+			// net/http 约定用于中断响应, 交由 http.Server 处理
+			if r == http.ErrAbortHandler {
+				panic(r)
+			}
+
+			err, ok := r.(error)
+			if !ok {
+				err = fmt.Errorf("%v", r)
+			}
+			stack := string(debug.Stack())
+
+			ctx := c.Request.Context()
+			traceID, spanID := trace2.TraceAndSpanIDFromContext(ctx)
+
+			logrus.WithFields(logrus.Fields{
+				"tag":         "panic",
+				"method":      c.Request.Method,
+				"request_url": c.Request.URL.String(),
+				"trace_id":    traceID,
+				"span_id":     spanID,
+				"stack":       stack,
+			}).Errorf("panic recovered: %v", r)
+
+			if span := trace2.GetTraceSpanFromContext(ctx); span != nil {
+				span.TraceSpan().RecordError(err, trace.WithAttributes(
+					attribute.String("exception.stacktrace", stack),
+					attribute.Bool("exception.escaped", false),
+				))
+				span.TraceSpan().SetStatus(codes.Error, err.Error())
+			}
+
+			if c.Writer.Written() {
+				c.Abort()
+				return
+			}
+
+			statusErr := internalServerError(err).
+				WithID(traceID).
+				AppendSource(confx.Config.ServiceName())
+
+			c.AbortWithStatusJSON(statusErr.StatusCode(), statusErr)
+		}()
+
+		c.Next()
+	}
+}
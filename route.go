@@ -0,0 +1,88 @@
+package confserver
+
+import (
+	"context"
+	"path"
+	"reflect"
+	"strings"
+)
+
+// RouteOption 类型化路由的 OpenAPI 描述
+type RouteOption func(op *routeOperation)
+
+// WithSummary operation 摘要
+func WithSummary(summary string) RouteOption {
+	return func(op *routeOperation) {
+		op.summary = summary
+	}
+}
+
+// WithDescription operation 描述
+func WithDescription(description string) RouteOption {
+	return func(op *routeOperation) {
+		op.description = description
+	}
+}
+
+// WithTags operation 分组
+func WithTags(tags ...string) RouteOption {
+	return func(op *routeOperation) {
+		op.tags = append(op.tags, tags...)
+	}
+}
+
+// WithOperationID 默认为请求结构体类型名
+func WithOperationID(operationID string) RouteOption {
+	return func(op *routeOperation) {
+		op.operationID = operationID
+	}
+}
+
+// WithDeprecated 标记 operation 已废弃
+func WithDeprecated() RouteOption {
+	return func(op *routeOperation) {
+		op.deprecated = true
+	}
+}
+
+type routeOperation struct {
+	method      string
+	path        string
+	operationID string
+	summary     string
+	description string
+	tags        []string
+	deprecated  bool
+	req         reflect.Type
+	resp        reflect.Type
+}
+
+// RegisterRoute 以 Handle(fn) 在 SvcRootRouter() 下注册路由, 并记录请求/响应类型,
+// 未提供 OpenAPISpec 时 OpenAPI 文档按已注册的路由生成, 需在 Init 后调用
+func RegisterRoute[Req any, Resp any](s *Server, method string, relativePath string, fn func(ctx context.Context, req *Req) (Resp, error), opts ...RouteOption) {
+	root := s.SvcRootRouter()
+	root.Handle(method, relativePath, Handle(fn))
+
+	op := &routeOperation{
+		method: strings.ToUpper(method),
+		path:   joinRoutePath(root.BasePath(), relativePath),
+		req:    reflect.TypeOf((*Req)(nil)).Elem(),
+		resp:   reflect.TypeOf((*Resp)(nil)).Elem(),
+	}
+	for _, opt := range opts {
+		opt(op)
+	}
+	s.openapi.addOperation(op)
+}
+
+// joinRoutePath 与 gin RouterGroup 拼接路径的规则一致, 保留结尾的 /
+func joinRoutePath(basePath string, relativePath string) string {
+	if relativePath == "" {
+		return basePath
+	}
+	p := path.Join(basePath, relativePath)
+	if strings.HasSuffix(relativePath, "/") && !strings.HasSuffix(p, "/") {
+		return p + "/"
+	}
+	return p
+}
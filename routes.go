@@ -0,0 +1,79 @@
+package confserver
+
+import (
+	"net/http"
+	"reflect"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// RouteInfo 已注册的路由, Middlewares 为 engine 的全局中间件,
+// gin 未公开 group 中间件, 不包含在内
+type RouteInfo struct {
+	Method      string   `json:"method"`
+	Path        string   `json:"path"`
+	Handler     string   `json:"handler"`
+	Middlewares []string `json:"middlewares"`
+}
+
+// Routes 列出 engine 上注册的所有路由, 按 path 和 method 排序
+func (s *Server) Routes() []RouteInfo {
+	globalMiddlewares := make([]string, len(s.r.Handlers))
+	for i := range s.r.Handlers {
+		globalMiddlewares[i] = nameOfFunction(reflect.ValueOf(s.r.Handlers[i]))
+	}
+
+	routes := s.r.Routes()
+	infos := make([]RouteInfo, len(routes))
+	for i, route := range routes {
+		infos[i] = RouteInfo{
+			Method:      route.Method,
+			Path:        route.Path,
+			Handler:     route.Handler,
+			Middlewares: globalMiddlewares,
+		}
+	}
+
+	sort.Slice(infos, func(i, j int) bool {
+		if infos[i].Path != infos[j].Path {
+			return infos[i].Path < infos[j].Path
+		}
+		return infos[i].Method < infos[j].Method
+	})
+	return infos
+}
+
+// RoutesHandler 以 JSON 输出 Routes()
+func (s *Server) RoutesHandler(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, s.Routes())
+}
+
+func (s *Server) routeInventoryEnabled() bool {
+	return s.EnableRouteInventory || strings.ToLower(s.Mode) == "debug"
+}
+
+// logRoutes 启动时输出路由汇总
+func (s *Server) logRoutes() {
+	routes := s.Routes()
+	for _, route := range routes {
+		logrus.WithFields(logrus.Fields{
+			"tag":         "routes",
+			"method":      route.Method,
+			"path":        route.Path,
+			"handler":     route.Handler,
+			"middlewares": len(route.Middlewares),
+		}).Info("route")
+	}
+	logrus.WithField("tag", "routes").Infof("%d routes registered", len(routes))
+}
+
+func nameOfFunction(fn reflect.Value) string {
+	if fn.Kind() != reflect.Func || fn.IsNil() {
+		return ""
+	}
+	return runtime.FuncForPC(fn.Pointer()).Name()
+}
@@ -0,0 +1,88 @@
+package confserver
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func routezTestHandler(c *gin.Context) {
+	c.Status(http.StatusOK)
+}
+
+func TestRoutesHandler(t *testing.T) {
+	cases := []struct {
+		name    string
+		server  *Server
+		enabled bool
+	}{
+		{name: "disabled by default", server: &Server{}, enabled: false},
+		{name: "enabled", server: &Server{EnableRouteInventory: true}, enabled: true},
+		{name: "debug mode", server: &Server{Mode: "debug"}, enabled: true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			defer gin.SetMode(gin.TestMode)
+
+			s := c.server
+			s.SetDefaults()
+			s.Init()
+			s.SvcRootRouter().POST("/users", routezTestHandler)
+
+			rw := httptest.NewRecorder()
+			s.Engine().ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/routez", nil))
+
+			if !c.enabled {
+				if rw.Code != http.StatusNotFound {
+					t.Fatalf("status = %d, want 404", rw.Code)
+				}
+				return
+			}
+			if rw.Code != http.StatusOK {
+				t.Fatalf("status = %d, want 200", rw.Code)
+			}
+
+			var routes []RouteInfo
+			if err := json.Unmarshal(rw.Body.Bytes(), &routes); err != nil {
+				t.Fatal(err)
+			}
+
+			if len(routes) != len(s.Engine().Routes()) {
+				t.Fatalf("routes = %d, want %d", len(routes), len(s.Engine().Routes()))
+			}
+			for i := 1; i < len(routes); i++ {
+				prev, cur := routes[i-1], routes[i]
+				if prev.Path > cur.Path || prev.Path == cur.Path && prev.Method > cur.Method {
+					t.Fatalf("routes not sorted: %v %v", prev, cur)
+				}
+			}
+
+			usersPath := joinRoutePath(s.SvcRootRouter().BasePath(), "/users")
+			var users *RouteInfo
+			for i := range routes {
+				if routes[i].Method == http.MethodPost && routes[i].Path == usersPath {
+					users = &routes[i]
+				}
+			}
+			if users == nil {
+				t.Fatalf("POST %s missing in %v", usersPath, routes)
+			}
+			if !strings.HasSuffix(users.Handler, ".routezTestHandler") {
+				t.Fatalf("handler = %s", users.Handler)
+			}
+			if len(users.Middlewares) != len(s.Engine().Handlers) {
+				t.Fatalf("middlewares = %v, want %d global middlewares", users.Middlewares, len(s.Engine().Handlers))
+			}
+			for _, name := range users.Middlewares {
+				if name == "" {
+					t.Fatalf("empty middleware name in %v", users.Middlewares)
+				}
+			}
+		})
+	}
+}
@@ -2,21 +2,22 @@ package confserver
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"io"
+	"io/fs"
 	"net/http"
-	"os"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/gin-contrib/gzip"
 
 	"github.com/gin-contrib/pprof"
 
 	"github.com/gin-gonic/gin"
+	trace2 "github.com/kunlun-qilian/confserver/pkg/trace"
 	"github.com/kunlun-qilian/confx"
-	swaggerFiles "github.com/swaggo/files"
-	ginSwagger "github.com/swaggo/gin-swagger"
+	"github.com/sirupsen/logrus"
 )
 
 type Server struct {
@@ -24,12 +25,62 @@ type Server struct {
 	Mode            string `env:""`
 	HealthCheckPath string `env:",opt,healthCheck"`
 	OpenAPISpec     string `env:",opt,copy"`
-	UseH2C          bool   `env:""`
-	// 跨域
+	// 检查 OpenAPISpec 文件变更并重新加载
+	WatchOpenAPISpec bool `env:""`
+	// OpenAPISpec 文件检查间隔, 默认 5s
+	OpenAPISpecReloadInterval Duration `env:""`
+	// RequestValidation 按 OpenAPISpec 校验请求, 支持 enforce / report, 默认关闭
+	RequestValidation string `env:""`
+	// MockServer 按 OpenAPISpec 注册所有 operation 并返回示例数据, 用于本地联调
+	MockServer bool `env:""`
+	// 文档页面 {RootPath}/docs, debug 模式默认开启
+	EnableDocs bool `env:""`
+	// DocsUI 支持 swagger / redoc, 默认 swagger
+	DocsUI string `env:""`
+	// DocsAssetsURL 文档页面静态资源地址, 默认使用 CDN, 内网环境可指向自建镜像
+	DocsAssetsURL string `env:""`
+	// 路由列表 /routez, debug 模式默认开启
+	EnableRouteInventory bool `env:""`
+	UseH2C               bool `env:""`
+	// TLS 证书, 与 TLSKeyFile 同时配置时启用 https, 仅配置其一时 Serve 返回错误
+	TLSCertFile string `env:""`
+	TLSKeyFile  string `env:""`
+	// 客户端证书 CA, 配置后默认要求并校验客户端证书
+	ClientCAFile string `env:""`
+	// ClientAuth 支持 none / request / require / verify-if-given / require-and-verify
+	ClientAuth string `env:""`
+	// 证书文件检查间隔, 证书变更后自动加载
+	TLSReloadInterval Duration `env:""`
+	// Prometheus 指标 默认关闭
+	EnableMetrics bool `env:""`
+	// 指标路径, 默认 /metrics
+	MetricsPath string `env:""`
+	// 指标单独监听的端口, 默认与服务共用端口
+	MetricsPort int `env:""`
+	// 优雅退出的最长时间, 包括等待处理中的请求完成及导出剩余的 trace / 指标, 默认 30s
+	ShutdownTimeout Duration `env:""`
+	// 跨域 开启后按 Cors 配置校验
 	CorsCheck bool
+	Cors      CORS
+	// multipart 上传 超过后写入临时文件, 默认 32MB
+	MultipartMaxMemory ByteSize `env:""`
+	// multipart 上传 单个请求上限, 默认不限制
+	MultipartMaxRequestSize ByteSize `env:""`
+	// multipart 上传 单个文件上限, 默认不限制
+	MultipartMaxFileSize ByteSize `env:""`
 	// 流式返回 取消压缩
 	Compress bool
 	r        *gin.Engine
+	certs    *certReloader
+	metrics  *serverMetrics
+	openapi  *openAPISpec
+	// OpenAPI spec 来源 见 SetOpenAPIFS
+	openapiFS     fs.FS
+	openapiFSName string
+	docsAssetsFS  fs.FS
+	// health check registry
+	health     *healthRegistry
+	healthOnce sync.Once
 	// healthCheckUpdated
 	healthCheckUpdated bool
 }
@@ -45,15 +96,55 @@ func (s *Server) SetDefaults() {
 
 	gin.SetMode(s.Mode)
 
+	if s.ShutdownTimeout == 0 {
+		s.ShutdownTimeout = Duration(30 * time.Second)
+	}
+
+	if s.DocsUI == "" {
+		s.DocsUI = DocsUISwagger
+	}
+
 	if s.OpenAPISpec == "" {
 		s.OpenAPISpec = "./openapi.json"
 	}
 
+	if s.MetricsPath == "" {
+		s.MetricsPath = "/metrics"
+	}
+
+	if s.MultipartMaxMemory == 0 {
+		s.MultipartMaxMemory = 32 << 20
+	}
+
+	if s.TLSReloadInterval == 0 {
+		s.TLSReloadInterval = Duration(time.Minute)
+	}
+
+	if s.OpenAPISpecReloadInterval == 0 {
+		s.OpenAPISpecReloadInterval = Duration(5 * time.Second)
+	}
+
+	// 与 gin.SetMode 一致, 未知的配置直接 panic
+	s.RequestValidation = strings.ToLower(s.RequestValidation)
+	switch s.RequestValidation {
+	case "", ValidationEnforce, ValidationReport:
+	default:
+		panic(fmt.Errorf("unknown RequestValidation %q, expect %s or %s", s.RequestValidation, ValidationEnforce, ValidationReport))
+	}
+
+	if s.ClientCAFile != "" && s.ClientAuth == "" {
+		s.ClientAuth = ClientAuthRequireAndVerify
+	}
+
 	if !s.healthCheckUpdated {
+		scheme := "http"
+		if s.tlsEnabled() {
+			scheme = "https"
+		}
 		if s.HealthCheckPath == "" {
-			s.HealthCheckPath = fmt.Sprintf("http://:%d/healthz", s.Port)
+			s.HealthCheckPath = fmt.Sprintf("%s://:%d/healthz", scheme, s.Port)
 		} else {
-			s.HealthCheckPath = fmt.Sprintf("http://:%d%s", s.Port, s.HealthCheckPath)
+			s.HealthCheckPath = fmt.Sprintf("%s://:%d%s", scheme, s.Port, s.HealthCheckPath)
 		}
 		s.healthCheckUpdated = true
 	}
@@ -73,95 +164,195 @@ func (s *Server) Init() {
 	}
 	// cors
 	if s.CorsCheck {
-		s.r.Use(DefaultCORS())
+		s.r.Use(NewCORS(s.Cors))
 	} else {
 		s.r.Use(AllowAllCors())
 	}
 
+	// mTLS client certificate
+	if s.tlsEnabled() {
+		s.r.Use(ClientCertHandler())
+	}
+
+	// metrics
+	if s.EnableMetrics {
+		s.metrics = newServerMetrics()
+		s.r.Use(s.MetricsMiddleware())
+		if s.MetricsPort == 0 {
+			s.r.GET(s.MetricsPath, gin.WrapH(s.MetricsHandler()))
+		}
+	}
+
 	// log
 	s.r.Use(LoggerHandler())
 	// trace
-	s.r.Use(TraceHandler())
+	s.r.Use(TraceHandler(s.skipTracePaths()...))
+	// otel metrics
+	s.r.Use(HTTPMetricsHandler())
+	// recovery
+	s.r.Use(RecoveryHandler())
+
+	// multipart upload limits
+	s.r.Use(s.multipartLimitsHandler())
+
+	s.openapi = newOpenAPISpec(s.OpenAPISpec, s.openapiFS, s.openAPIFSName())
+	// openapi request validation
+	if s.RequestValidation != "" {
+		s.r.Use(s.OpenAPIRequestValidator(s.RequestValidation))
+	}
+	// openapi response contract check
+	if strings.ToLower(s.Mode) == "debug" {
+		s.r.Use(s.OpenAPIResponseValidator())
+	}
 
 	// health check
 	s.r.GET("/healthz", s.HealthCheck)
+	s.r.GET("/livez", s.ProbeHandler(ProbeLiveness))
+	s.r.GET("/readyz", s.ProbeHandler(ProbeReadiness))
+	s.r.GET("/startupz", s.ProbeHandler(ProbeStartup))
 	// openapi
 	s.r.GET(fmt.Sprintf("/%s", strings.TrimPrefix(confx.Config.ServiceName(), "srv-")), s.OpenapiHandler)
+	if s.docsEnabled() {
+		root := s.SvcRootRouter()
+		root.GET("/openapi", s.OpenapiHandler)
+		root.GET("/docs", s.DocsHandler)
+		if s.docsAssetsFS != nil {
+			root.StaticFS("/docs/assets", http.FS(s.docsAssetsFS))
+		}
+	}
+	if s.routeInventoryEnabled() {
+		s.r.GET("/routez", s.RoutesHandler)
+	}
 	if strings.ToLower(s.Mode) == "debug" {
-		s.r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 		pprof.Register(s.r)
 	}
 }
 
+// skipTracePaths Server 自身注册在主端口上的 metrics 及 routez 路由
+func (s *Server) skipTracePaths() []string {
+	var paths []string
+	if s.EnableMetrics && s.MetricsPort == 0 {
+		paths = append(paths, s.MetricsPath)
+	}
+	if s.routeInventoryEnabled() {
+		paths = append(paths, "/routez")
+	}
+	return paths
+}
+
 func (s *Server) Engine() *gin.Engine {
 	return s.r
 }
 
 func (s *Server) serve(ctx context.Context) error {
-	return s.r.Run(fmt.Sprintf(":%d", s.Port))
-}
-
-func (s *Server) Serve(ctx context.Context, fn ...func(ctx context.Context) error) (err error) {
-	wg := &sync.WaitGroup{}
-	serverList := []func(ctx context.Context) error{
-		s.serve,
+	if err := s.validateTLS(); err != nil {
+		return err
 	}
 
-	if len(fn) != 0 {
-		serverList = append(serverList, fn...)
+	// 启动时加载 OpenAPI 文档, 无 OpenAPISpec 时按 RegisterRoute 注册的路由生成
+	if _, err := s.openapi.Doc(); err != nil && !errors.Is(err, errOpenAPISpecNotFound) {
+		logrus.WithField("tag", "openapi").WithError(err).Error("load openapi spec failed")
+	}
+	if s.WatchOpenAPISpec {
+		go s.openapi.watch(ctx, time.Duration(s.OpenAPISpecReloadInterval))
+	}
+	// mock 路由在服务注册完自身路由后补齐, 已实现的 operation 不再 mock
+	if s.MockServer {
+		s.registerMockRoutes()
 	}
+	s.logRoutes()
 
-	for i := range serverList {
-		wg.Add(1)
-		go func(s func(ctx context.Context) error) {
-			defer wg.Done()
+	srv := &http.Server{
+		Addr:    fmt.Sprintf(":%d", s.Port),
+		Handler: s.r.Handler(),
+	}
 
-			if e := s(ctx); e != nil {
-				err = e
-			}
-		}(serverList[i])
+	if s.tlsEnabled() {
+		tlsConfig, err := s.newTLSConfig()
+		if err != nil {
+			return err
+		}
+		srv.TLSConfig = tlsConfig
+		go s.certs.watch(ctx, time.Duration(s.TLSReloadInterval))
 	}
-	wg.Wait()
-	return
-}
 
-func (s *Server) SvcRootRouter() *gin.RouterGroup {
-	return s.r.Group(confx.RootPath)
+	return s.listenAndServe(ctx, srv)
 }
 
-func (s *Server) HealthCheck(ctx *gin.Context) {
-	ctx.Data(200, "text/plain; charset=utf-8", []byte(confx.Config.ServiceName()))
+// listenAndServe ctx 取消后停止接收新连接, 并在 ShutdownTimeout 内等待处理中的请求完成
+func (s *Server) listenAndServe(ctx context.Context, srv *http.Server) error {
+	errCh := make(chan error, 1)
+	go func() {
+		defer close(errCh)
+
+		var err error
+		if srv.TLSConfig != nil {
+			err = srv.ListenAndServeTLS("", "")
+		} else {
+			err = srv.ListenAndServe()
+		}
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			errCh <- err
+		}
+	}()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+	}
+
+	// stop accepting and drain in-flight requests
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), time.Duration(s.ShutdownTimeout))
+	defer cancel()
+
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		// 超时后强制关闭剩余连接, 避免 Serve 返回后仍有请求在处理
+		_ = srv.Close()
+		return err
+	}
+	return <-errCh
 }
 
-func (s *Server) OpenapiHandler(ctx *gin.Context) {
+// Serve 启动 http server 及 fn 中的其他服务, ctx 取消后优雅退出.
+// 任一服务返回错误或 panic 时其余服务会收到取消信号,
+// 所有服务退出后关闭已 Init 的 Trace / Metric, 返回合并后的错误.
+// 请求的 drain 与 Trace / Metric 的导出共用 ShutdownTimeout, 从收到取消信号时开始计算
+func (s *Server) Serve(ctx context.Context, fn ...func(ctx context.Context) error) error {
+	g, ctx := newGroup(ctx)
 
-	file, err := os.Open(s.OpenAPISpec)
-	defer func() {
-		_ = file.Close()
-	}()
-	if err != nil {
-		ctx.Data(200, "text/plain; charset=utf-8", nil)
-		return
+	stopped := make(chan time.Time, 1)
+	context.AfterFunc(ctx, func() {
+		stopped <- time.Now()
+	})
+
+	g.Go(ctx, s.serve)
+	if s.EnableMetrics && s.MetricsPort != 0 {
+		g.Go(ctx, s.serveMetrics)
+	}
+	for i := range fn {
+		g.Go(ctx, fn[i])
 	}
 
-	var openapiByte []byte
+	err := g.Wait()
 
-	contentByte, err := io.ReadAll(file)
-	if err != nil {
-		ctx.Data(http.StatusOK, "text/plain; charset=utf-8", nil)
-		return
+	// 所有服务退出后在剩余的时间内导出剩余的 span 及指标, Wait 返回时 ctx 已取消
+	shutdownCtx, cancel := context.WithDeadline(context.Background(), (<-stopped).Add(time.Duration(s.ShutdownTimeout)))
+	defer cancel()
+
+	if shutdownErr := trace2.Shutdown(shutdownCtx); shutdownErr != nil {
+		logrus.WithField("tag", "trace").WithError(shutdownErr).Error("shutdown trace provider failed")
 	}
-	openapiByte = contentByte
+	return err
+}
 
-	q, exist := ctx.Get("format")
-	// yaml format
-	if exist && q.(string) == "yaml" {
-		yamlByte, err := JSONToYAML(contentByte)
-		if err != nil {
-			ctx.Data(200, "text/plain; charset=utf-8", nil)
-			return
-		}
-		openapiByte = yamlByte
+func (s *Server) SvcRootRouter() *gin.RouterGroup {
+	return s.r.Group(confx.RootPath)
+}
+
+func (s *Server) HealthCheck(ctx *gin.Context) {
+	if s.certs != nil {
+		ctx.Header("X-TLS-Certificate-Expiry", s.certs.NotAfter().Format(time.RFC3339))
 	}
-	ctx.Data(http.StatusOK, "text/plain; charset=utf-8", openapiByte)
+	ctx.Data(200, "text/plain; charset=utf-8", []byte(confx.Config.ServiceName()))
 }
@@ -0,0 +1,244 @@
+package confserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	trace2 "github.com/kunlun-qilian/confserver/pkg/trace"
+	"go.opentelemetry.io/otel"
+)
+
+func freePort(t testing.TB) int {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	return l.Addr().(*net.TCPAddr).Port
+}
+
+// ShutdownTimeout 内未完成的请求在 Serve 返回前被强制关闭
+func TestServeShutdownTimeoutClosesConnections(t *testing.T) {
+	s := &Server{Port: freePort(t), ShutdownTimeout: Duration(50 * time.Millisecond)}
+	s.SetDefaults()
+	s.Init()
+
+	started := make(chan struct{})
+	released := make(chan struct{})
+	s.Engine().GET("/slow", func(c *gin.Context) {
+		close(started)
+		<-c.Request.Context().Done()
+		close(released)
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() {
+		served <- s.Serve(ctx)
+	}()
+
+	go func() {
+		for {
+			resp, err := http.Get(fmt.Sprintf("http://127.0.0.1:%d/slow", s.Port))
+			if err == nil {
+				resp.Body.Close()
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}()
+
+	select {
+	case <-started:
+	case <-time.After(2 * time.Second):
+		t.Fatal("server not started")
+	}
+	cancel()
+
+	select {
+	case err := <-served:
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("Serve err = %v, want deadline exceeded", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Serve not returned")
+	}
+
+	select {
+	case <-released:
+	case <-time.After(time.Second):
+		t.Fatal("in-flight request still running after Serve returned")
+	}
+}
+
+func TestServerRequestValidationMode(t *testing.T) {
+	cases := []struct {
+		mode  string
+		want  string
+		panic bool
+	}{
+		{mode: "", want: ""},
+		{mode: "enforce", want: ValidationEnforce},
+		{mode: "Report", want: ValidationReport},
+		{mode: "true", panic: true},
+		{mode: "1", panic: true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.mode, func(t *testing.T) {
+			defer func() {
+				if r := recover(); (r != nil) != c.panic {
+					t.Fatalf("panic = %v, want panic %v", r, c.panic)
+				}
+			}()
+
+			s := &Server{RequestValidation: c.mode}
+			s.SetDefaults()
+			if s.RequestValidation != c.want {
+				t.Fatalf("RequestValidation = %q, want %q", s.RequestValidation, c.want)
+			}
+		})
+	}
+}
+
+// newTestCollector OTLP http collector, block 为 true 时不响应导出请求直到测试结束
+func newTestCollector(t *testing.T, block bool) (*trace2.Trace, <-chan string) {
+	done := make(chan struct{})
+	paths := make(chan string, 16)
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case paths <- r.URL.Path:
+		default:
+		}
+		if block {
+			select {
+			case <-done:
+			case <-r.Context().Done():
+			}
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(func() {
+		close(done)
+		collector.Close()
+	})
+
+	prev := otel.GetTracerProvider()
+	t.Cleanup(func() {
+		otel.SetTracerProvider(prev)
+	})
+
+	conf := &trace2.Trace{
+		OTLPEndpoint: strings.TrimPrefix(collector.URL, "http://"),
+		Insecure:     true,
+		ServiceName:  "srv-test",
+		DisableRetry: true,
+	}
+	conf.Init()
+	return conf, paths
+}
+
+// waitServing 请求 path 直到 Server 开始服务
+func waitServing(t *testing.T, port int, path string) {
+	t.Helper()
+
+	for deadline := time.Now().Add(2 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		resp, err := http.Get(fmt.Sprintf("http://127.0.0.1:%d%s", port, path))
+		if err == nil {
+			resp.Body.Close()
+			return
+		}
+	}
+	t.Fatal("server not started")
+}
+
+// Serve 退出前导出剩余的 span
+func TestServeShutsDownTrace(t *testing.T) {
+	_, paths := newTestCollector(t, false)
+
+	s := &Server{Port: freePort(t)}
+	s.SetDefaults()
+	s.Init()
+	s.Engine().GET("/ping", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() {
+		served <- s.Serve(ctx)
+	}()
+	waitServing(t, s.Port, "/ping")
+	cancel()
+
+	if err := <-served; err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case p := <-paths:
+		if p != "/v1/traces" {
+			t.Fatalf("export path = %s", p)
+		}
+	default:
+		t.Fatal("span not exported before Serve returned")
+	}
+}
+
+// 请求的 drain 与 trace 的导出共用 ShutdownTimeout
+func TestServeShutdownTimeoutIncludesTraceFlush(t *testing.T) {
+	newTestCollector(t, true)
+
+	shutdownTimeout := 300 * time.Millisecond
+	s := &Server{Port: freePort(t), ShutdownTimeout: Duration(shutdownTimeout)}
+	s.SetDefaults()
+	s.Init()
+
+	started := make(chan struct{}, 1)
+	s.Engine().GET("/slow", func(c *gin.Context) {
+		started <- struct{}{}
+		<-c.Request.Context().Done()
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() {
+		served <- s.Serve(ctx)
+	}()
+	go func() {
+		// 请求在 drain 超时后被强制关闭, 启动前连接失败时重试
+		for i := 0; i < 200; i++ {
+			resp, err := http.Get(fmt.Sprintf("http://127.0.0.1:%d/slow", s.Port))
+			if err == nil {
+				resp.Body.Close()
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}()
+
+	select {
+	case <-started:
+	case <-time.After(2 * time.Second):
+		t.Fatal("server not started")
+	}
+
+	stoppedAt := time.Now()
+	cancel()
+
+	select {
+	case <-served:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Serve not returned")
+	}
+	if elapsed := time.Since(stoppedAt); elapsed > shutdownTimeout+shutdownTimeout/2 {
+		t.Fatalf("Serve returned after %s, want within ShutdownTimeout %s", elapsed, shutdownTimeout)
+	}
+}
@@ -0,0 +1,120 @@
+package confserver
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	ClientAuthNone             = "none"
+	ClientAuthRequest          = "request"
+	ClientAuthRequire          = "require"
+	ClientAuthVerifyIfGiven    = "verify-if-given"
+	ClientAuthRequireAndVerify = "require-and-verify"
+)
+
+func (s *Server) tlsEnabled() bool {
+	return s.TLSCertFile != "" && s.TLSKeyFile != ""
+}
+
+// validateTLS 仅配置部分证书或未启用 https 时配置了 mTLS, 避免静默回退为 http
+func (s *Server) validateTLS() error {
+	if (s.TLSCertFile == "") != (s.TLSKeyFile == "") {
+		return errors.New("TLSCertFile and TLSKeyFile must be configured together")
+	}
+	if !s.tlsEnabled() && (s.ClientCAFile != "" || (s.ClientAuth != "" && strings.ToLower(s.ClientAuth) != ClientAuthNone)) {
+		return errors.New("ClientCAFile and ClientAuth require TLSCertFile and TLSKeyFile")
+	}
+	return nil
+}
+
+func (s *Server) newTLSConfig() (*tls.Config, error) {
+	certs, err := newCertReloader(s.TLSCertFile, s.TLSKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("load tls key pair: %w", err)
+	}
+	s.certs = certs
+
+	clientAuth, err := parseClientAuth(s.ClientAuth)
+	if err != nil {
+		return nil, err
+	}
+
+	conf := &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: certs.GetCertificate,
+		ClientAuth:     clientAuth,
+	}
+
+	if s.ClientCAFile != "" {
+		caPEM, err := os.ReadFile(s.ClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("read client ca: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caPEM) {
+			return nil, fmt.Errorf("no certificates found in client ca %s", s.ClientCAFile)
+		}
+		conf.ClientCAs = pool
+	}
+
+	return conf, nil
+}
+
+func parseClientAuth(mode string) (tls.ClientAuthType, error) {
+	switch strings.ToLower(mode) {
+	case "", ClientAuthNone:
+		return tls.NoClientCert, nil
+	case ClientAuthRequest:
+		return tls.RequestClientCert, nil
+	case ClientAuthRequire:
+		return tls.RequireAnyClientCert, nil
+	case ClientAuthVerifyIfGiven:
+		return tls.VerifyClientCertIfGiven, nil
+	case ClientAuthRequireAndVerify:
+		return tls.RequireAndVerifyClientCert, nil
+	}
+	return tls.NoClientCert, fmt.Errorf("unknown client auth mode %q", mode)
+}
+
+const contextKeyClientCertificate = "confserver.clientCertificate"
+
+// ClientCertHandler 将校验通过的客户端证书注入 gin context
+func ClientCertHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if cert := verifiedClientCertificate(c); cert != nil {
+			c.Set(contextKeyClientCertificate, cert)
+		}
+		c.Next()
+	}
+}
+
+// ClientCertificate 获取校验通过的客户端证书, 未启用 mTLS 或客户端未提供证书时返回 nil
+func ClientCertificate(c *gin.Context) *x509.Certificate {
+	if v, ok := c.Get(contextKeyClientCertificate); ok {
+		return v.(*x509.Certificate)
+	}
+	return verifiedClientCertificate(c)
+}
+
+// ClientCertSubject 客户端证书 subject, 无证书时返回空字符串
+func ClientCertSubject(c *gin.Context) string {
+	if cert := ClientCertificate(c); cert != nil {
+		return cert.Subject.String()
+	}
+	return ""
+}
+
+func verifiedClientCertificate(c *gin.Context) *x509.Certificate {
+	state := c.Request.TLS
+	if state == nil || len(state.VerifiedChains) == 0 || len(state.VerifiedChains[0]) == 0 {
+		return nil
+	}
+	return state.VerifiedChains[0][0]
+}
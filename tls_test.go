@@ -0,0 +1,348 @@
+package confserver
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+type testCert struct {
+	cert    *x509.Certificate
+	key     *ecdsa.PrivateKey
+	certPEM []byte
+	keyPEM  []byte
+}
+
+// newTestCA 自签名 CA
+func newTestCA(t testing.TB) *testCert {
+	return newTestCert(t, nil, &x509.Certificate{
+		Subject:               pkix.Name{CommonName: "test-ca"},
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	})
+}
+
+// issue 签发 127.0.0.1 可用的服务端或客户端证书
+func (ca *testCert) issue(t testing.TB, cn string, notAfter time.Time, usage x509.ExtKeyUsage) *testCert {
+	return newTestCert(t, ca, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: cn},
+		NotAfter:    notAfter,
+		DNSNames:    []string{"localhost"},
+		IPAddresses: []net.IP{net.ParseIP("127.0.0.1")},
+		KeyUsage:    x509.KeyUsageDigitalSignature,
+		ExtKeyUsage: []x509.ExtKeyUsage{usage},
+	})
+}
+
+func newTestCert(t testing.TB, parent *testCert, tmpl *x509.Certificate) *testCert {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl.SerialNumber = serial
+	tmpl.NotBefore = time.Now().Add(-time.Hour)
+
+	parentCert, parentKey := tmpl, key
+	if parent != nil {
+		parentCert, parentKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, parentCert, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &testCert{
+		cert:    cert,
+		key:     key,
+		certPEM: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		keyPEM:  pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+	}
+}
+
+// writeTo 写入 dir/name.crt 及 dir/name.key
+func (c *testCert) writeTo(t testing.TB, dir string, name string) (string, string) {
+	t.Helper()
+
+	certFile, keyFile := filepath.Join(dir, name+".crt"), filepath.Join(dir, name+".key")
+	if err := os.WriteFile(certFile, c.certPEM, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, c.keyPEM, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return certFile, keyFile
+}
+
+func (c *testCert) tlsCertificate(t testing.TB) tls.Certificate {
+	t.Helper()
+
+	cert, err := tls.X509KeyPair(c.certPEM, c.keyPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert
+}
+
+func TestParseClientAuth(t *testing.T) {
+	cases := []struct {
+		mode string
+		want tls.ClientAuthType
+		err  bool
+	}{
+		{mode: "", want: tls.NoClientCert},
+		{mode: ClientAuthNone, want: tls.NoClientCert},
+		{mode: ClientAuthRequest, want: tls.RequestClientCert},
+		{mode: ClientAuthRequire, want: tls.RequireAnyClientCert},
+		{mode: ClientAuthVerifyIfGiven, want: tls.VerifyClientCertIfGiven},
+		{mode: "Require-And-Verify", want: tls.RequireAndVerifyClientCert},
+		{mode: "optional", err: true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.mode, func(t *testing.T) {
+			got, err := parseClientAuth(c.mode)
+			if (err != nil) != c.err {
+				t.Fatalf("err = %v", err)
+			}
+			if got != c.want {
+				t.Fatalf("parseClientAuth(%q) = %v, want %v", c.mode, got, c.want)
+			}
+		})
+	}
+}
+
+func TestNewTLSConfig(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t)
+	certFile, keyFile := ca.issue(t, "server", time.Now().Add(time.Hour), x509.ExtKeyUsageServerAuth).writeTo(t, dir, "server")
+	caFile, _ := ca.writeTo(t, dir, "ca")
+
+	invalidFile := filepath.Join(dir, "invalid.pem")
+	if err := os.WriteFile(invalidFile, []byte("invalid"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name       string
+		server     *Server
+		clientAuth tls.ClientAuthType
+		clientCAs  bool
+		err        bool
+	}{
+		{name: "tls", server: &Server{TLSCertFile: certFile, TLSKeyFile: keyFile}, clientAuth: tls.NoClientCert},
+		{name: "mtls by client ca", server: &Server{TLSCertFile: certFile, TLSKeyFile: keyFile, ClientCAFile: caFile}, clientAuth: tls.RequireAndVerifyClientCert, clientCAs: true},
+		{name: "client auth", server: &Server{TLSCertFile: certFile, TLSKeyFile: keyFile, ClientCAFile: caFile, ClientAuth: ClientAuthVerifyIfGiven}, clientAuth: tls.VerifyClientCertIfGiven, clientCAs: true},
+		{name: "unknown client auth", server: &Server{TLSCertFile: certFile, TLSKeyFile: keyFile, ClientAuth: "optional"}, err: true},
+		{name: "missing key pair", server: &Server{TLSCertFile: filepath.Join(dir, "missing.crt"), TLSKeyFile: keyFile}, err: true},
+		{name: "mismatched key pair", server: &Server{TLSCertFile: caFile, TLSKeyFile: keyFile}, err: true},
+		{name: "missing client ca", server: &Server{TLSCertFile: certFile, TLSKeyFile: keyFile, ClientCAFile: filepath.Join(dir, "missing.pem")}, err: true},
+		{name: "invalid client ca", server: &Server{TLSCertFile: certFile, TLSKeyFile: keyFile, ClientCAFile: invalidFile}, err: true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			c.server.SetDefaults()
+
+			conf, err := c.server.newTLSConfig()
+			if c.err {
+				if err == nil {
+					t.Fatal("expect error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if conf.MinVersion != tls.VersionTLS12 {
+				t.Fatalf("MinVersion = %x", conf.MinVersion)
+			}
+			if conf.ClientAuth != c.clientAuth {
+				t.Fatalf("ClientAuth = %v, want %v", conf.ClientAuth, c.clientAuth)
+			}
+			if (conf.ClientCAs != nil) != c.clientCAs {
+				t.Fatalf("ClientCAs = %v", conf.ClientCAs)
+			}
+			if cert, _ := conf.GetCertificate(nil); cert == nil || cert.Leaf.Subject.CommonName != "server" {
+				t.Fatalf("unexpected certificate %v", cert)
+			}
+		})
+	}
+}
+
+// 证书配置不完整时 Serve 返回错误, 而不是以 http 启动
+func TestServeInvalidTLSConfig(t *testing.T) {
+	cases := []struct {
+		name   string
+		server *Server
+	}{
+		{name: "cert only", server: &Server{TLSCertFile: "server.crt"}},
+		{name: "key only", server: &Server{TLSKeyFile: "server.key"}},
+		{name: "client ca without cert", server: &Server{ClientCAFile: "ca.crt"}},
+		{name: "client auth without cert", server: &Server{ClientAuth: ClientAuthRequire}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			s := c.server
+			s.Port = freePort(t)
+			s.SetDefaults()
+			s.Init()
+
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+
+			if err := s.Serve(ctx); err == nil {
+				t.Fatal("expect error")
+			}
+			if ctx.Err() != nil {
+				t.Fatal("Serve should fail before serving")
+			}
+		})
+	}
+}
+
+// 与 ClientCertHandler 及 TraceHandler 一起校验客户端证书的注入及 span 属性
+func TestServeMutualTLS(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	prev := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	defer otel.SetTracerProvider(prev)
+
+	dir := t.TempDir()
+	ca := newTestCA(t)
+	certFile, keyFile := ca.issue(t, "server", time.Now().Add(time.Hour), x509.ExtKeyUsageServerAuth).writeTo(t, dir, "server")
+	caFile, _ := ca.writeTo(t, dir, "ca")
+	client := ca.issue(t, "client-a", time.Now().Add(time.Hour), x509.ExtKeyUsageClientAuth)
+
+	s := &Server{Port: freePort(t), TLSCertFile: certFile, TLSKeyFile: keyFile, ClientCAFile: caFile}
+	s.SetDefaults()
+	s.Init()
+	s.Engine().GET("/whoami", func(c *gin.Context) {
+		c.String(http.StatusOK, ClientCertSubject(c))
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() {
+		served <- s.Serve(ctx)
+	}()
+	defer func() {
+		cancel()
+		<-served
+	}()
+
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.cert)
+
+	newClient := func(certs ...tls.Certificate) *http.Client {
+		return &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool, Certificates: certs}}}
+	}
+	url := fmt.Sprintf("https://127.0.0.1:%d/whoami", s.Port)
+
+	var (
+		resp *http.Response
+		err  error
+	)
+	for deadline := time.Now().Add(2 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if resp, err = newClient(client.tlsCertificate(t)).Get(url); err == nil {
+			break
+		}
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK || string(body) != "CN=client-a" {
+		t.Fatalf("response = %d %s, want 200 CN=client-a", resp.StatusCode, body)
+	}
+
+	spans := recorder.Ended()
+	if len(spans) == 0 {
+		t.Fatal("no span recorded")
+	}
+	attrs := map[string]string{}
+	for _, attr := range spans[len(spans)-1].Attributes() {
+		attrs[string(attr.Key)] = attr.Value.Emit()
+	}
+	if attrs["tls.client.subject"] != "CN=client-a" || attrs["tls.client.issuer"] != "CN=test-ca" || attrs["tls.client.serial_number"] != client.cert.SerialNumber.String() {
+		t.Fatalf("unexpected span attributes %v", attrs)
+	}
+
+	// 默认 require-and-verify, 未提供客户端证书时握手失败
+	if resp, err := newClient().Get(url); err == nil {
+		resp.Body.Close()
+		t.Fatalf("expect handshake error, got %d", resp.StatusCode)
+	}
+}
+
+func TestClientCertHandler(t *testing.T) {
+	ca := newTestCA(t)
+	client := ca.issue(t, "client-a", time.Now().Add(time.Hour), x509.ExtKeyUsageClientAuth)
+
+	cases := []struct {
+		name    string
+		state   *tls.ConnectionState
+		subject string
+	}{
+		{name: "plain http", state: nil, subject: ""},
+		{name: "unverified certificate", state: &tls.ConnectionState{PeerCertificates: []*x509.Certificate{client.cert}}, subject: ""},
+		{name: "verified certificate", state: &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{client.cert, ca.cert}}}, subject: "CN=client-a"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var subject string
+			r := gin.New()
+			r.Use(ClientCertHandler())
+			r.GET("/", func(ctx *gin.Context) {
+				subject = ClientCertSubject(ctx)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.TLS = c.state
+			r.ServeHTTP(httptest.NewRecorder(), req)
+
+			if subject != c.subject {
+				t.Fatalf("subject = %q, want %q", subject, c.subject)
+			}
+		})
+	}
+}
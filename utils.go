@@ -3,15 +3,64 @@ package confserver
 import (
 	"context"
 	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	jsoniter "github.com/json-iterator/go"
+	trace2 "github.com/kunlun-qilian/confserver/pkg/trace"
 	"gopkg.in/yaml.v2"
 )
 
 var j = jsoniter.ConfigCompatibleWithStandardLibrary
 
+// Duration time.Duration 支持 "30s" 形式的环境变量配置
+type Duration = trace2.Duration
+
+// ByteSize 字节数 支持 "512", "64KB", "10MB", "1GB" 形式的配置, 按 1024 进制换算
+type ByteSize int64
+
+var byteSizeUnits = []struct {
+	suffix string
+	size   ByteSize
+}{
+	{"GB", 1 << 30},
+	{"MB", 1 << 20},
+	{"KB", 1 << 10},
+	{"B", 1},
+}
+
+func (b ByteSize) MarshalText() ([]byte, error) {
+	for _, u := range byteSizeUnits {
+		if b != 0 && b%u.size == 0 {
+			return []byte(strconv.FormatInt(int64(b/u.size), 10) + u.suffix), nil
+		}
+	}
+	return []byte(strconv.FormatInt(int64(b), 10)), nil
+}
+
+func (b *ByteSize) UnmarshalText(data []byte) error {
+	s := strings.ToUpper(strings.TrimSpace(string(data)))
+	if s == "" {
+		*b = 0
+		return nil
+	}
+	unit := ByteSize(1)
+	for _, u := range byteSizeUnits {
+		if strings.HasSuffix(s, u.suffix) {
+			s, unit = strings.TrimSpace(strings.TrimSuffix(s, u.suffix)), u.size
+			break
+		}
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || n < 0 {
+		return fmt.Errorf("invalid byte size %q", data)
+	}
+	*b = ByteSize(n) * unit
+	return nil
+}
+
 func ReprOfDuration(duration time.Duration) string {
 	return fmt.Sprintf("%.2fms", float32(duration)/float32(time.Microsecond)/1000)
 }